@@ -0,0 +1,123 @@
+// Command ankiexport writes Anki .apkg decks for the built-in lessons.
+//
+// By default it writes one deck per language into the current directory;
+// -per-lesson also writes one deck per lesson. Audio is taken from the TTS
+// cache in the data directory, so run the server with GOOGLE_TTS_API_KEY set
+// (and play the words) first if the decks should include pronunciation.
+//
+//	go run ./cmd/ankiexport -out decks -lang serbian -per-lesson
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"speakeasy/internal/anki"
+	"speakeasy/internal/langpack"
+	"speakeasy/internal/lessons"
+	"speakeasy/internal/tts"
+
+	// Register language packages
+	_ "speakeasy/internal/lessons/croatian"
+	_ "speakeasy/internal/lessons/indonesian"
+	_ "speakeasy/internal/lessons/serbian"
+)
+
+func main() {
+	defaultData := os.Getenv("SPEAKEASY_DATA_DIR")
+	if defaultData == "" {
+		defaultData = "."
+	}
+
+	outDir := flag.String("out", ".", "directory to write .apkg files to")
+	lang := flag.String("lang", "", "only export this language slug (default: all)")
+	perLesson := flag.Bool("per-lesson", false, "also write one deck per lesson")
+	dataDir := flag.String("data", defaultData, "data directory holding tts_cache and language packs")
+	webDir := flag.String("web", "web", "web directory holding static/audio overrides")
+	noAudio := flag.Bool("no-audio", false, "leave audio out of the decks")
+	flag.Parse()
+
+	if errs := lessons.LoadErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "ankiexport: %v\n", err)
+		}
+		os.Exit(1)
+	}
+
+	packs, packErrs := langpack.LoadDir(filepath.Join(*dataDir, "languages"))
+	for _, err := range packErrs {
+		fmt.Fprintf(os.Stderr, "ankiexport: %v\n", err)
+	}
+
+	var audio anki.AudioSource
+	if !*noAudio {
+		client := tts.NewClient(
+			filepath.Join(*dataDir, "tts_cache"),
+			filepath.Join(*webDir, "static", "audio"),
+		)
+		for _, p := range packs {
+			client.AddOverrides(p.Language.TTSCode, p.FS, p.Audio)
+		}
+		audio = client
+	}
+
+	var langs []lessons.Language
+	for _, l := range lessons.GetLanguages() {
+		if *lang == "" || l.Slug == *lang {
+			langs = append(langs, l)
+		}
+	}
+	if len(langs) == 0 {
+		fmt.Fprintf(os.Stderr, "ankiexport: unknown language %q\n", *lang)
+		os.Exit(2)
+	}
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "ankiexport: %v\n", err)
+		os.Exit(1)
+	}
+
+	failed := false
+	for i := range langs {
+		l := &langs[i]
+		if err := write(*outDir, "speakeasy-"+l.Slug, anki.LanguagePackage(l, audio)); err != nil {
+			fmt.Fprintf(os.Stderr, "ankiexport: %s: %v\n", l.Slug, err)
+			failed = true
+		}
+		if !*perLesson {
+			continue
+		}
+		for _, lesson := range lessons.GetLessons(l.Slug, false) {
+			if lesson.Checkpoint != nil {
+				continue
+			}
+			pkg := anki.LessonsPackage(l, []*lessons.Lesson{lesson}, audio)
+			if err := write(*outDir, "speakeasy-"+l.Slug+"-"+lesson.ID, pkg); err != nil {
+				fmt.Fprintf(os.Stderr, "ankiexport: %s/%s: %v\n", l.Slug, lesson.ID, err)
+				failed = true
+			}
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func write(dir, name string, pkg *anki.Package) error {
+	path := filepath.Join(dir, name+".apkg")
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := pkg.WriteAPKG(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("%s: %d notes, %d audio files\n", path, len(pkg.Notes), len(pkg.Media))
+	return nil
+}
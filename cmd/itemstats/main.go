@@ -0,0 +1,99 @@
+// Command itemstats writes the quiz item analysis as CSV: for every quiz
+// question that has been answered, the percent correct, the discrimination
+// index, how often each multiple-choice option was picked, and flags for
+// questions that look confusing or miskeyed. It is the same report as the
+// admin page at /admin/questions.
+//
+// It reads the server's database without changing it, so it can run against
+// a live instance.
+//
+//	go run ./cmd/itemstats -data /var/lib/speakeasy -lang serbian -flagged > items.csv
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"speakeasy/internal/db"
+	"speakeasy/internal/itemstats"
+	"speakeasy/internal/langpack"
+	"speakeasy/internal/lessons"
+
+	// Register language packages
+	_ "speakeasy/internal/lessons/croatian"
+	_ "speakeasy/internal/lessons/indonesian"
+	_ "speakeasy/internal/lessons/serbian"
+
+	_ "modernc.org/sqlite"
+)
+
+func main() {
+	defaultData := os.Getenv("SPEAKEASY_DATA_DIR")
+	if defaultData == "" {
+		defaultData = "."
+	}
+
+	dataDir := flag.String("data", defaultData, "data directory holding speakeasy.db and language packs")
+	lang := flag.String("lang", "", "only report this language slug (default: all)")
+	flagged := flag.Bool("flagged", false, "only report questions with flags")
+	out := flag.String("out", "", "file to write (default: standard output)")
+	flag.Parse()
+
+	if errs := lessons.LoadErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "itemstats: %v\n", err)
+		}
+		os.Exit(1)
+	}
+
+	// Language packs, so their questions are named in the report
+	_, packErrs := langpack.LoadDir(filepath.Join(*dataDir, "languages"))
+	for _, err := range packErrs {
+		fmt.Fprintf(os.Stderr, "itemstats: %v\n", err)
+	}
+
+	dbPath := filepath.Join(*dataDir, "speakeasy.db")
+	if _, err := os.Stat(dbPath); err != nil {
+		fmt.Fprintf(os.Stderr, "itemstats: %v\n", err)
+		os.Exit(1)
+	}
+	database, err := sql.Open("sqlite", "file:"+dbPath+"?mode=ro&_pragma=busy_timeout(5000)")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "itemstats: open database: %v\n", err)
+		os.Exit(1)
+	}
+	defer database.Close()
+
+	responses, err := itemstats.Load(context.Background(), db.New(database))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "itemstats: %v (has \"speakeasy migrate up\" been run?)\n", err)
+		os.Exit(1)
+	}
+
+	var items []itemstats.Item
+	for _, it := range itemstats.Analyze(responses) {
+		if (*lang == "" || it.Language == *lang) && (!*flagged || len(it.Flags) > 0) {
+			items = append(items, it)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "itemstats: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := itemstats.WriteCSV(w, items); err != nil {
+		fmt.Fprintf(os.Stderr, "itemstats: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,376 @@
+// Command lessonimport turns a vocabulary spreadsheet, saved as CSV or TSV,
+// into a lesson file: a vocab section with one word per row and a starter
+// quiz generated from the words.
+//
+//	go run ./cmd/lessonimport -lang serbian -title "At the Market" market.csv > lesson06.json
+//
+// The first row may name the columns, in any order: english, target (or the
+// language's name), alt, hint, example, example_english and example_alt. Without a header row the
+// columns are taken in that order. Files ending in .tsv, or whose first line
+// has a tab, are read as tab-separated.
+//
+// Word IDs are made from the target word folded to ASCII ("Doviđenja" becomes
+// "dovidjenja"), so importing an edited sheet again keeps the IDs, and the
+// progress learners have on them. For languages written in two scripts an
+// empty alt column is transliterated when the language has a converter
+// (Serbian Latin to Cyrillic). The quiz takes its distractors from the sheet
+// and the language's existing lessons; review it before publishing. The
+// output is checked like any lesson file before it is written.
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"speakeasy/internal/langpack"
+	"speakeasy/internal/lessons"
+	"speakeasy/internal/translit"
+
+	// Register language packages
+	_ "speakeasy/internal/lessons/croatian"
+	_ "speakeasy/internal/lessons/indonesian"
+	_ "speakeasy/internal/lessons/serbian"
+)
+
+func main() {
+	defaultData := os.Getenv("SPEAKEASY_DATA_DIR")
+	if defaultData == "" {
+		defaultData = "."
+	}
+
+	lang := flag.String("lang", "", "language slug the lesson is for (required)")
+	id := flag.String("id", "", `lesson id (default: "lesson" and the order, e.g. lesson06)`)
+	title := flag.String("title", "", "lesson title (default: from the file name)")
+	description := flag.String("description", "", "lesson description")
+	order := flag.Int("order", 0, "lesson order (default: after the language's last lesson)")
+	illustration := flag.String("illustration", "", "illustration file name, e.g. market.svg")
+	section := flag.String("section", "Vocabulary", "title of the vocab section")
+	draft := flag.Bool("draft", false, "mark the lesson as a draft, shown only to admins")
+	questions := flag.Int("questions", 8, "multiple-choice and listen-and-choose questions in the starter quiz")
+	seed := flag.Uint64("seed", 1, "seed for picking quiz distractors and answer positions")
+	out := flag.String("out", "", "file to write (default: standard output)")
+	dataDir := flag.String("data", defaultData, "data directory holding language packs")
+	flag.Parse()
+
+	if *lang == "" || flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: lessonimport -lang slug [flags] vocab.csv")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	file := flag.Arg(0)
+
+	if errs := lessons.LoadErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "lessonimport: %v\n", err)
+		}
+		os.Exit(1)
+	}
+	_, packErrs := langpack.LoadDir(filepath.Join(*dataDir, "languages"))
+	for _, err := range packErrs {
+		fmt.Fprintf(os.Stderr, "lessonimport: %v\n", err)
+	}
+	language := lessons.GetLanguage(*lang)
+	if language == nil {
+		fmt.Fprintf(os.Stderr, "lessonimport: unknown language %q\n", *lang)
+		os.Exit(2)
+	}
+
+	rows, err := readRows(file, *language)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lessonimport: %v\n", err)
+		os.Exit(1)
+	}
+	words := vocabItems(*language, rows)
+
+	l := &lessons.Lesson{
+		ID:           *id,
+		Title:        *title,
+		Description:  *description,
+		Order:        *order,
+		Illustration: *illustration,
+		Draft:        *draft,
+		Sections:     []lessons.Section{{Type: "vocab", Title: *section, Items: words}},
+	}
+	existing := lessons.GetAllLessons(language.Slug)
+	if l.Order == 0 {
+		l.Order = 1
+		if len(existing) > 0 {
+			last := existing[len(existing)-1]
+			l.Order = last.Order + 1
+			l.Prerequisite = &last.ID
+		}
+	}
+	if l.ID == "" {
+		l.ID = fmt.Sprintf("lesson%02d", l.Order)
+	}
+	if l.Title == "" {
+		l.Title = titleFromFile(file)
+	}
+
+	var pool []lessons.VocabItem
+	for _, w := range lessons.GetWords(language.Slug) {
+		pool = append(pool, w.VocabItem)
+	}
+	l.Quiz.Questions = append(
+		lessons.GenerateQuestions(*language, l, pool, lessons.Generate{Count: *questions, Types: []string{"multiple_choice", "listen_and_choose"}}, *seed),
+		lessons.GenerateQuestions(*language, l, nil, lessons.Generate{Count: 1, Types: []string{"match_pairs"}}, *seed)...,
+	)
+
+	data, err := lessons.Encode(l)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lessonimport: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := lessons.Parse(l.ID+".json", data); err != nil {
+		for _, e := range lessons.Split(err) {
+			fmt.Fprintf(os.Stderr, "lessonimport: %v\n", e)
+		}
+		os.Exit(1)
+	}
+
+	if *out == "" {
+		os.Stdout.Write(data)
+	} else if err := os.WriteFile(*out, data, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "lessonimport: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "lessonimport: %s: %d words, %d quiz questions\n", l.ID, len(words), len(l.Quiz.Questions))
+}
+
+// row is one word of the spreadsheet.
+type row struct {
+	line                                int
+	english, target, alt, hint          string
+	example, exampleEnglish, exampleAlt string
+}
+
+// columns are the spreadsheet columns in their default order, and
+// columnAliases other names a header may use for them.
+var (
+	columns       = []string{"english", "target", "alt", "hint", "example", "example_english", "example_alt"}
+	columnAliases = map[string]string{
+		"target_primary":     "target",
+		"target_alt":         "alt",
+		"pronunciation":      "hint",
+		"pronunciation_hint": "hint",
+		"example_en":         "example_english",
+	}
+)
+
+// readRows reads the words from a CSV or TSV file, skipping blank rows.
+func readRows(path string, lang lessons.Language) ([]row, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// Spreadsheet programs often start UTF-8 exports with a byte order mark
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	r := csv.NewReader(bytes.NewReader(data))
+	firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+	if strings.EqualFold(filepath.Ext(path), ".tsv") || bytes.ContainsRune(firstLine, '\t') {
+		r.Comma = '\t'
+		r.LazyQuotes = true
+	}
+	r.FieldsPerRecord = -1
+
+	index := make(map[string]int, len(columns))
+	for i, c := range columns {
+		index[c] = i
+	}
+	var rows []row
+	for first := true; ; first = false {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if first {
+			if header, ok := parseHeader(record, lang); ok {
+				index = header
+				continue
+			}
+		}
+		line, _ := r.FieldPos(0)
+		cell := func(name string) string {
+			i, ok := index[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		w := row{
+			line:           line,
+			english:        cell("english"),
+			target:         cell("target"),
+			alt:            cell("alt"),
+			hint:           cell("hint"),
+			example:        cell("example"),
+			exampleEnglish: cell("example_english"),
+			exampleAlt:     cell("example_alt"),
+		}
+		if w == (row{line: line}) {
+			continue
+		}
+		if w.english == "" || w.target == "" {
+			return nil, fmt.Errorf("%s:%d: a word needs english and target", path, line)
+		}
+		rows = append(rows, w)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s: no words", path)
+	}
+	return rows, nil
+}
+
+// parseHeader returns the column positions named by record, or false if it
+// is a word rather than a header row. Besides the column names and their
+// aliases, the target column may be named after the language or its primary
+// script and the alt column after the alternate script.
+func parseHeader(record []string, lang lessons.Language) (map[string]int, bool) {
+	index := make(map[string]int)
+	for i, cell := range record {
+		name := strings.ToLower(strings.TrimSpace(cell))
+		if name == "" {
+			continue
+		}
+		if alias, ok := columnAliases[name]; ok {
+			name = alias
+		}
+		switch {
+		case name == lang.Slug || strings.EqualFold(name, lang.DisplayName) || strings.EqualFold(name, lang.ScriptLabel):
+			name = "target"
+		case lang.AltScriptLabel != "" && strings.EqualFold(name, lang.AltScriptLabel):
+			name = "alt"
+		}
+		known := false
+		for _, c := range columns {
+			known = known || c == name
+		}
+		if !known {
+			return nil, false
+		}
+		if _, dup := index[name]; !dup {
+			index[name] = i
+		}
+	}
+	_, hasEnglish := index["english"]
+	_, hasTarget := index["target"]
+	return index, hasEnglish && hasTarget
+}
+
+// vocabItems converts rows to vocab items with unique IDs, filling in the
+// alternate script where the language has a converter.
+func vocabItems(lang lessons.Language, rows []row) []lessons.VocabItem {
+	toAlt, canConvert := translit.For(lang.Slug)
+	alt := func(given, primary string) string {
+		if given != "" || !lang.HasDualScript || !canConvert || primary == "" {
+			return given
+		}
+		return toAlt(primary)
+	}
+
+	used := make(map[string]bool, len(rows))
+	items := make([]lessons.VocabItem, len(rows))
+	for i, r := range rows {
+		base := wordID(r.target, r.english)
+		id := base
+		for n := 2; used[id]; n++ {
+			id = fmt.Sprintf("%s_%d", base, n)
+		}
+		used[id] = true
+
+		items[i] = lessons.VocabItem{
+			ID:                id,
+			English:           r.english,
+			TargetPrimary:     r.target,
+			TargetAlt:         alt(r.alt, r.target),
+			PronunciationHint: r.hint,
+		}
+		if r.example != "" {
+			items[i].ExampleSentence = &lessons.ExampleSentence{
+				English:       r.exampleEnglish,
+				TargetPrimary: r.example,
+				TargetAlt:     alt(r.exampleAlt, r.example),
+			}
+		}
+	}
+	if lang.HasDualScript && !canConvert {
+		for _, it := range items {
+			if it.TargetAlt == "" {
+				fmt.Fprintf(os.Stderr, "lessonimport: no %s transliteration: fill in the alt column\n", lang.DisplayName)
+				break
+			}
+		}
+	}
+	return items
+}
+
+// asciiFold spells letters with diacritics the way word IDs do.
+var asciiFold = map[rune]string{
+	'č': "c", 'ć': "c", 'š': "s", 'ž': "z", 'đ': "dj",
+	'á': "a", 'à': "a", 'â': "a", 'ä': "a", 'ã': "a", 'å': "a",
+	'é': "e", 'è': "e", 'ê': "e", 'ë': "e", 'ě': "e",
+	'í': "i", 'ì': "i", 'î': "i", 'ï': "i",
+	'ó': "o", 'ò': "o", 'ô': "o", 'ö': "o", 'õ': "o", 'ő': "o", 'ø': "o",
+	'ú': "u", 'ù': "u", 'û': "u", 'ü': "u", 'ű': "u", 'ů': "u",
+	'ñ': "n", 'ň': "n", 'ç': "c", 'ř': "r", 'ť': "t", 'ď': "d", 'ý': "y",
+	'ł': "l", 'ß': "ss",
+}
+
+// wordID makes a stable ID from a word: its first alternative ("Otac / Tata"
+// gives "otac"), lowercased and folded to ASCII, with words joined by
+// underscores. Words with nothing left to spell it, such as ones written in
+// another alphabet, use their English meaning instead.
+func wordID(target, english string) string {
+	for _, text := range []string{target, english} {
+		text, _, _ = strings.Cut(text, "/")
+		var sb strings.Builder
+		pendingSep := false
+		for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+			var s string
+			switch {
+			case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+				s = string(r)
+			case asciiFold[r] != "":
+				s = asciiFold[r]
+			case unicode.IsSpace(r) || r == '-' || r == '_':
+				pendingSep = sb.Len() > 0
+				continue
+			default:
+				continue
+			}
+			if pendingSep {
+				sb.WriteByte('_')
+				pendingSep = false
+			}
+			sb.WriteString(s)
+		}
+		if sb.Len() > 0 {
+			return sb.String()
+		}
+	}
+	return "word"
+}
+
+// titleFromFile makes a lesson title from a file name: "at-the-market.csv"
+// gives "At the market".
+func titleFromFile(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	name = strings.TrimSpace(strings.NewReplacer("_", " ", "-", " ").Replace(name))
+	if name == "" {
+		return "New lesson"
+	}
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
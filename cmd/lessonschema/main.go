@@ -0,0 +1,68 @@
+// Command lessonschema writes the JSON Schema for lesson files, generated from
+// the lessons package types. The published copy is
+// internal/lessons/lesson.schema.json; regenerate it after changing the types:
+//
+//	go generate ./internal/lessons
+//
+// With -check it instead validates lesson files the way the server loads
+// them, printing every problem with its file and JSON path:
+//
+//	go run ./cmd/lessonschema -check internal/lessons/serbian/data/*.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"speakeasy/internal/lessons"
+)
+
+func main() {
+	out := flag.String("out", "", "file to write (default: standard output)")
+	check := flag.Bool("check", false, "validate the lesson files given as arguments instead")
+	flag.Parse()
+
+	if *check {
+		os.Exit(checkFiles(flag.Args()))
+	}
+
+	data, err := lessons.Schema()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lessonschema: %v\n", err)
+		os.Exit(1)
+	}
+	if *out == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "lessonschema: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// checkFiles validates each file and returns the exit status.
+func checkFiles(files []string) int {
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "lessonschema: -check needs lesson files")
+		return 2
+	}
+	status := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lessonschema: %v\n", err)
+			status = 1
+			continue
+		}
+		if _, err := lessons.Parse(filepath.ToSlash(file), data); err != nil {
+			for _, e := range lessons.Split(err) {
+				fmt.Println(e)
+			}
+			status = 1
+		}
+	}
+	return status
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"speakeasy/internal/db"
+)
+
+// runAdmin implements "speakeasy admin [grant|revoke] <username>" and returns
+// the process exit code. It is how the first admin is created; after that,
+// admins can grant the role from /admin.
+func runAdmin(queries *db.Queries, args []string) int {
+	if len(args) != 2 || (args[0] != "grant" && args[0] != "revoke") {
+		fmt.Fprintln(os.Stderr, "usage: speakeasy admin [grant|revoke] <username>")
+		return 2
+	}
+	ctx := context.Background()
+
+	user, err := queries.GetUserByUsername(ctx, args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "admin %s: no user %q: %v\n", args[0], args[1], err)
+		return 1
+	}
+	grant := args[0] == "grant"
+	if err := queries.SetUserAdmin(ctx, db.SetUserAdminParams{IsAdmin: grant, ID: user.ID}); err != nil {
+		fmt.Fprintf(os.Stderr, "admin %s: %v\n", args[0], err)
+		return 1
+	}
+
+	if grant {
+		fmt.Printf("%s is now an admin.\n", user.Username)
+	} else {
+		fmt.Printf("%s is no longer an admin.\n", user.Username)
+	}
+	return 0
+}
@@ -16,7 +16,10 @@ import (
 
 	"speakeasy/internal/db"
 	"speakeasy/internal/handlers"
+	"speakeasy/internal/langpack"
+	"speakeasy/internal/lessons"
 	"speakeasy/internal/middleware"
+	"speakeasy/internal/orphans"
 	"speakeasy/internal/tts"
 
 	// Register language packages
@@ -59,7 +62,10 @@ func main() {
 
 	// Open SQLite database (pure Go driver)
 	dbPath := filepath.Join(dataDir, "speakeasy.db")
-	database, err := sql.Open("sqlite", dbPath)
+	// busy_timeout lets concurrent writers wait for the lock instead of failing,
+	// and _txlock=immediate takes the write lock at BEGIN so read-modify-write
+	// transactions cannot deadlock upgrading from a read lock.
+	database, err := sql.Open("sqlite", dbPath+"?_pragma=busy_timeout(5000)&_txlock=immediate")
 	if err != nil {
 		log.Fatalf("Failed to open database: %v", err)
 	}
@@ -69,12 +75,23 @@ func main() {
 	database.Exec("PRAGMA journal_mode=WAL")
 	database.Exec("PRAGMA foreign_keys=ON")
 
-	// Initialize schema
-	if err := initSchema(database); err != nil {
-		log.Fatalf("Failed to initialize schema: %v", err)
+	// "speakeasy migrate ..." manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(database, os.Args[2:]))
+	}
+
+	// Bring the schema up to date before serving
+	if err := checkSchema(database); err != nil {
+		log.Fatalf("Failed to migrate schema: %v", err)
 	}
 
 	queries := db.New(database)
+
+	// "speakeasy admin ..." grants or revokes the admin role and exits
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		os.Exit(runAdmin(queries, os.Args[2:]))
+	}
+
 	sessions := middleware.NewSessionStore()
 
 	// Determine template and static directories
@@ -93,25 +110,69 @@ func main() {
 	audioDir := filepath.Join(staticDir, "audio")
 	ttsClient := tts.NewClient(cacheDir, audioDir)
 
+	// Problems in the built-in lesson files. In development mode the broken
+	// language is loaded once its files are fixed; otherwise refuse to start
+	// without it.
+	if errs := lessons.LoadErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			slog.Error("invalid lesson", "error", err)
+		}
+		if !lessons.DevMode() {
+			log.Fatalf("Failed to load lessons: %d problem(s)", len(errs))
+		}
+	}
+
+	// Language packs in the data directory, registered alongside the
+	// built-in languages
+	packs, packErrs := langpack.LoadDir(filepath.Join(dataDir, "languages"))
+	for _, err := range packErrs {
+		slog.Error("skipped language pack", "error", err)
+	}
+	for _, p := range packs {
+		ttsClient.AddOverrides(p.Language.TTSCode, p.FS, p.Audio)
+	}
+
+	// Vocabulary saved under word IDs the lessons no longer define, after a
+	// word was renamed or removed; fixed from /admin/lessons
+	if words, err := orphans.Find(context.Background(), queries); err != nil {
+		slog.Error("check saved vocabulary", "error", err)
+	} else if len(words) > 0 {
+		slog.Warn("saved vocabulary for words no lesson defines; move or delete it at /admin/lessons", "words", len(words))
+	}
+
 	// Determine production mode
 	isProd := strings.EqualFold(os.Getenv("PROD"), "true")
 
 	// Template renderer
 	tmpl := handlers.NewTemplateRenderer(templatesDir)
 
+	// Development mode: lessons (see lessons.DevMode) and templates are read
+	// from disk and reloaded when they change
+	if lessons.DevMode() {
+		tmpl.ReloadFromDisk()
+		go lessons.WatchDev(time.Second)
+		slog.Info("dev mode: reloading lessons and templates from disk")
+	}
+
 	// Handlers
 	authHandler := handlers.NewAuthHandler(queries, sessions, tmpl, isProd)
 	lessonHandler := handlers.NewLessonHandler(queries, tmpl)
-	quizHandler := handlers.NewQuizHandler(queries, tmpl)
+	quizHandler := handlers.NewQuizHandler(database, queries, tmpl)
+	wordsHandler := handlers.NewWordsHandler(queries, tmpl)
+	ankiHandler := handlers.NewAnkiHandler(ttsClient, tmpl)
+	statsHandler := handlers.NewStatsHandler(queries, tmpl)
 	progressHandler := handlers.NewProgressHandler(sessions)
+	activityHandler := handlers.NewActivityHandler(queries)
 	ttsHandler := handlers.NewTTSHandler(ttsClient)
 	birthdayHandler := handlers.NewBirthdayHandler(tmpl)
+	adminHandler := handlers.NewAdminHandler(database, queries, sessions, ttsClient, tmpl)
 
 	// Mux
 	mux := http.NewServeMux()
 
 	// Static files
 	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir(staticDir))))
+	mux.Handle("/languages/", langpack.AssetHandler(packs))
 
 	// Public routes
 	mux.HandleFunc("/", lessonHandler.Home)
@@ -134,11 +195,14 @@ func main() {
 
 	// Protected lesson routes — dynamic language pattern
 	// Matches /lessons/{language} for lesson list
+	// Matches /lessons/{language}/words for the vocabulary notebook
+	// Matches /lessons/{language}/placement for the placement test
+	// Matches /lessons/{language}/anki and /lessons/{language}/{lessonID}/anki for Anki decks
 	// Matches /lessons/{language}/{lessonID} and /lessons/{language}/{lessonID}/quiz
 	mux.HandleFunc("/lessons/", middleware.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
 		path := filepath.ToSlash(r.URL.Path)
 		parts := splitPath(path)
-		// parts[0] = "lessons", parts[1] = language, parts[2] = lessonID, parts[3] = "quiz"
+		// parts[0] = "lessons", parts[1] = language, parts[2] = lessonID, parts[3] = "quiz", parts[4] = "step" | "finish"
 
 		if len(parts) < 2 {
 			http.NotFound(w, r)
@@ -151,6 +215,63 @@ func main() {
 			return
 		}
 
+		// /lessons/{language}/anki — whole-language Anki deck
+		if parts[2] == "anki" {
+			ankiHandler.LanguageDeck(w, r)
+			return
+		}
+
+		// /lessons/{language}/{lessonID}/anki — single-lesson Anki deck
+		if len(parts) == 4 && parts[3] == "anki" {
+			ankiHandler.LessonDeck(w, r)
+			return
+		}
+
+		// /lessons/{language}/placement[/start] — placement test
+		if parts[2] == "placement" {
+			switch {
+			case len(parts) == 3 && r.Method == http.MethodPost:
+				quizHandler.AnswerPlacement(w, r)
+			case len(parts) == 3:
+				quizHandler.PlacementPage(w, r)
+			case parts[3] == "start" && r.Method == http.MethodPost:
+				quizHandler.StartPlacement(w, r)
+			default:
+				http.NotFound(w, r)
+			}
+			return
+		}
+
+		// /lessons/{language}/words[/star|/export] — vocabulary notebook
+		if parts[2] == "words" {
+			switch {
+			case len(parts) == 3:
+				wordsHandler.Page(w, r)
+			case parts[3] == "star" && r.Method == http.MethodPost:
+				wordsHandler.ToggleStar(w, r)
+			case parts[3] == "export":
+				wordsHandler.Export(w, r)
+			default:
+				http.NotFound(w, r)
+			}
+			return
+		}
+
+		// /lessons/{language}/{lessonID}/quiz/step — one question at a time
+		if len(parts) >= 5 && parts[3] == "quiz" {
+			switch {
+			case parts[4] == "step" && r.Method == http.MethodPost:
+				quizHandler.AnswerStep(w, r)
+			case parts[4] == "step":
+				quizHandler.StepPage(w, r)
+			case parts[4] == "finish" && r.Method == http.MethodPost:
+				quizHandler.FinishStep(w, r)
+			default:
+				http.NotFound(w, r)
+			}
+			return
+		}
+
 		// /lessons/{language}/{lessonID} or /lessons/{language}/{lessonID}/quiz
 		if len(parts) >= 4 && parts[3] == "quiz" {
 			if r.Method == http.MethodPost {
@@ -163,9 +284,31 @@ func main() {
 		}
 	}))
 
+	// Statistics — /stats redirects to the first language, /stats/{language}
+	mux.HandleFunc("/stats", middleware.RequireAuth(statsHandler.Index))
+	mux.HandleFunc("/stats/", middleware.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		if len(splitPath(r.URL.Path)) != 2 {
+			http.NotFound(w, r)
+			return
+		}
+		statsHandler.Page(w, r)
+	}))
+
+	// Admin area — /admin, /admin/questions[.csv], POST /admin/users/{id}/{action}
+	mux.HandleFunc("/admin", middleware.RequireAdmin(adminHandler.IsAdmin, adminHandler.Users))
+	mux.HandleFunc("/admin/questions", middleware.RequireAdmin(adminHandler.IsAdmin, adminHandler.Questions))
+	mux.HandleFunc("/admin/questions.csv", middleware.RequireAdmin(adminHandler.IsAdmin, adminHandler.QuestionsCSV))
+	mux.HandleFunc("/admin/users/", middleware.RequireAdmin(adminHandler.IsAdmin, adminHandler.UserAction))
+	mux.HandleFunc("/admin/lessons", middleware.RequireAdmin(adminHandler.IsAdmin, adminHandler.Lessons))
+	mux.HandleFunc("/admin/lessons/", middleware.RequireAdmin(adminHandler.IsAdmin, adminHandler.LessonEditor))
+	mux.HandleFunc("/admin/lessons/orphans", middleware.RequireAdmin(adminHandler.IsAdmin, adminHandler.OrphanAction))
+
 	// API routes
 	mux.HandleFunc("/api/tts", ttsHandler.ServeAudio)
 	mux.HandleFunc("/api/preference/script", middleware.RequireAuth(progressHandler.SetScriptPreference))
+	mux.HandleFunc("/api/preference/goal", middleware.RequireAuth(activityHandler.SetGoal))
+	mux.HandleFunc("/api/preference/timezone", middleware.RequireAuth(activityHandler.SetTimezone))
+	mux.HandleFunc("/api/activity/heartbeat", middleware.RequireAuth(activityHandler.Heartbeat))
 
 	// Middleware chain: security headers → request logging → auth → mux
 	handler := middleware.SecurityHeaders(isProd,
@@ -214,8 +357,3 @@ func splitPath(path string) []string {
 	}
 	return parts
 }
-
-func initSchema(database *sql.DB) error {
-	_, err := database.Exec(db.SchemaSQL)
-	return err
-}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"speakeasy/internal/db"
+)
+
+// checkSchema runs at startup. Pending migrations are applied unless
+// SPEAKEASY_AUTO_MIGRATE=false, in which case the server refuses to start
+// until "speakeasy migrate up" has been run.
+func checkSchema(database *sql.DB) error {
+	ctx := context.Background()
+
+	if strings.EqualFold(os.Getenv("SPEAKEASY_AUTO_MIGRATE"), "false") {
+		pending, err := db.PendingMigrations(ctx, database)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migration(s), starting with %04d_%s; run \"speakeasy migrate up\"",
+				len(pending), pending[0].Version, pending[0].Name)
+		}
+		return nil
+	}
+
+	applied, err := db.Migrate(ctx, database)
+	for _, m := range applied {
+		slog.Info("applied migration", "version", m.Version, "name", m.Name)
+	}
+	return err
+}
+
+// runMigrate implements "speakeasy migrate [status|up] [-dry-run]" and returns
+// the process exit code.
+func runMigrate(database *sql.DB, args []string) int {
+	cmd := "status"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("migrate "+cmd, flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "print pending migrations without applying them")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx := context.Background()
+
+	switch cmd {
+	case "status":
+		statuses, err := db.MigrationStatuses(ctx, database)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate status: %v\n", err)
+			return 1
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-30s  %s\n", s.Version, s.Name, state)
+		}
+		return 0
+
+	case "up":
+		if *dryRun {
+			pending, err := db.PendingMigrations(ctx, database)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "migrate up: %v\n", err)
+				return 1
+			}
+			if len(pending) == 0 {
+				fmt.Println("Schema is up to date.")
+				return 0
+			}
+			for _, m := range pending {
+				fmt.Printf("-- %04d_%s (dry run, not applied)\n%s\n", m.Version, m.Name, strings.TrimSpace(m.SQL))
+			}
+			return 0
+		}
+
+		applied, err := db.Migrate(ctx, database)
+		for _, m := range applied {
+			fmt.Printf("Applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate up: %v\n", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("Schema is up to date.")
+		}
+		return 0
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command %q (want status or up)\n", cmd)
+		return 2
+	}
+}
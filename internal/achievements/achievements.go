@@ -0,0 +1,147 @@
+// Package achievements declares the badges a learner can earn and decides
+// which ones a set of progress stats qualifies for.
+//
+// Most badges are a threshold on a Metric and live in badges.json, so a new
+// one is a JSON edit. Badges that need more than a threshold can be added
+// from Go with Register and a Check function. Earned badges are stored by ID
+// in user_achievements; no schema change is needed for new badges.
+package achievements
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Metric names a progress number that badges can be awarded on.
+type Metric string
+
+const (
+	LessonsCompleted   Metric = "lessons_completed"   // across all languages
+	PerfectQuizzes     Metric = "perfect_quizzes"     // quiz attempts scoring 100%
+	LongestStreak      Metric = "longest_streak"      // days, see activity.ComputeStreak
+	WordsMastered      Metric = "words_mastered"      // vocab at mastery 4 or above
+	LanguagesCompleted Metric = "languages_completed" // languages with every lesson completed
+)
+
+var metrics = map[Metric]bool{
+	LessonsCompleted:   true,
+	PerfectQuizzes:     true,
+	LongestStreak:      true,
+	WordsMastered:      true,
+	LanguagesCompleted: true,
+}
+
+// Stats are a user's current values for every Metric.
+type Stats map[Metric]int
+
+// Badge is one achievement. It is earned when Stats[Metric] reaches
+// Threshold, or, for Go-declared badges, when Check returns true.
+type Badge struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+	Metric      Metric `json:"metric"`
+	Threshold   int    `json:"threshold"`
+
+	Check func(Stats) bool `json:"-"`
+}
+
+// Earned reports whether s qualifies for the badge.
+func (b Badge) Earned(s Stats) bool {
+	if b.Check != nil {
+		return b.Check(s)
+	}
+	return s[b.Metric] >= b.Threshold
+}
+
+// Progress is how far s is towards a threshold badge, from 0 to 100.
+// Badges with a Check function report 0 until earned.
+func (b Badge) Progress(s Stats) int {
+	if b.Earned(s) {
+		return 100
+	}
+	if b.Check != nil || b.Threshold <= 0 {
+		return 0
+	}
+	return s[b.Metric] * 100 / b.Threshold
+}
+
+//go:embed badges.json
+var badgesJSON []byte
+
+var (
+	mu     sync.RWMutex
+	badges []Badge
+	byID   = make(map[string]Badge)
+)
+
+func init() {
+	var declared []Badge
+	if err := json.Unmarshal(badgesJSON, &declared); err != nil {
+		panic(fmt.Sprintf("achievements: parse badges.json: %v", err))
+	}
+	for _, b := range declared {
+		if err := Register(b); err != nil {
+			panic(fmt.Sprintf("achievements: badges.json: %v", err))
+		}
+	}
+}
+
+// Register adds a badge. IDs must be unique, and a badge needs either a
+// known Metric with a positive Threshold or a Check function.
+func Register(b Badge) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if b.ID == "" || b.Name == "" {
+		return fmt.Errorf("badge %q: id and name are required", b.ID)
+	}
+	if _, dup := byID[b.ID]; dup {
+		return fmt.Errorf("badge %q: duplicate id", b.ID)
+	}
+	if b.Check == nil {
+		if !metrics[b.Metric] {
+			return fmt.Errorf("badge %q: unknown metric %q", b.ID, b.Metric)
+		}
+		if b.Threshold <= 0 {
+			return fmt.Errorf("badge %q: threshold must be positive", b.ID)
+		}
+	}
+
+	badges = append(badges, b)
+	byID[b.ID] = b
+	return nil
+}
+
+// All returns every badge in declaration order.
+func All() []Badge {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	result := make([]Badge, len(badges))
+	copy(result, badges)
+	return result
+}
+
+// Get returns the badge with the given ID.
+func Get(id string) (Badge, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	b, ok := byID[id]
+	return b, ok
+}
+
+// Eligible returns every badge that s qualifies for.
+func Eligible(s Stats) []Badge {
+	var result []Badge
+	for _, b := range All() {
+		if b.Earned(s) {
+			result = append(result, b)
+		}
+	}
+	return result
+}
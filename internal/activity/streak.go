@@ -0,0 +1,228 @@
+// Package activity turns a user's daily activity into XP, streaks and the
+// dashboard heatmap. Days are calendar dates in the user's own timezone,
+// formatted with DateLayout, so "today" flips over at the user's midnight.
+package activity
+
+import (
+	"time"
+)
+
+// DateLayout is how days are stored in daily_activity.day.
+const DateLayout = "2006-01-02"
+
+// XP awards. A quiz earns a base amount plus a tenth of its score, each vocab
+// word answered counts as one review, and lesson reading earns one XP per
+// minute the page is open and visible.
+const (
+	QuizBaseXP  = 10
+	ReviewXP    = 1
+	XPPerMinute = 1
+)
+
+// DefaultGoal is the daily XP goal for new users; GoalOptions are the goals a
+// user can choose from.
+const DefaultGoal = 20
+
+var GoalOptions = []Goal{
+	{XP: 10, Label: "Casual"},
+	{XP: 20, Label: "Regular"},
+	{XP: 30, Label: "Serious"},
+	{XP: 50, Label: "Intense"},
+}
+
+type Goal struct {
+	XP    int64
+	Label string
+}
+
+// ValidGoal reports whether xp is one of GoalOptions.
+func ValidGoal(xp int64) bool {
+	for _, g := range GoalOptions {
+		if g.XP == xp {
+			return true
+		}
+	}
+	return false
+}
+
+// FreezeCooldown is how many days must pass after a streak freeze is used
+// before another one is available. A freeze is spent automatically on a day
+// the goal was missed, so one missed day never resets a streak on its own.
+const FreezeCooldown = 7
+
+// QuizXP is the XP for submitting a quiz with the given percentage score.
+func QuizXP(score int) int64 {
+	return QuizBaseXP + int64(score)/10
+}
+
+// Location loads a user's IANA timezone, falling back to UTC for empty or
+// unknown names.
+func Location(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Today is the current date in loc.
+func Today(loc *time.Location) string {
+	return time.Now().In(loc).Format(DateLayout)
+}
+
+// Day is one date's activity. Goal is the user's goal as it was that day,
+// so changing the goal later doesn't rewrite history.
+type Day struct {
+	Date string
+	XP   int64
+	Goal int64
+}
+
+// Met reports whether the day's goal was reached.
+func (d Day) Met() bool {
+	return d.Goal > 0 && d.XP >= d.Goal
+}
+
+// Streak summarises a user's habit as of today.
+type Streak struct {
+	Current         int
+	Longest         int
+	TodayXP         int64
+	Goal            int64
+	GoalMet         bool
+	FreezeAvailable bool
+	FrozenDays      []string // missed days bridged by a freeze, oldest first
+}
+
+// GoalPercent is today's progress towards the goal, capped at 100.
+func (s Streak) GoalPercent() int {
+	if s.Goal <= 0 {
+		return 0
+	}
+	pct := int(s.TodayXP * 100 / s.Goal)
+	if pct > 100 {
+		pct = 100
+	}
+	return pct
+}
+
+// ComputeStreak walks every day from the first recorded activity up to today.
+// A day extends the streak when its goal was met. A missed day spends the
+// freeze if one is available (the streak survives but doesn't grow) and
+// otherwise resets the streak. Today never breaks a streak: it is still
+// in progress. goal is the user's current goal, used for today.
+func ComputeStreak(days []Day, today string, goal int64) Streak {
+	s := Streak{Goal: goal}
+
+	byDate := make(map[string]Day, len(days))
+	first := today
+	for _, d := range days {
+		byDate[d.Date] = d
+		if d.Date < first {
+			first = d.Date
+		}
+	}
+
+	start, err := time.Parse(DateLayout, first)
+	if err != nil {
+		return s
+	}
+	end, err := time.Parse(DateLayout, today)
+	if err != nil {
+		return s
+	}
+
+	streak := 0
+	lastFreeze := time.Time{}
+	var frozen []string
+	for t := start; !t.After(end); t = t.AddDate(0, 0, 1) {
+		date := t.Format(DateLayout)
+		day := byDate[date]
+		if date == today {
+			day.Goal = goal
+			s.TodayXP = day.XP
+			s.GoalMet = day.Met()
+		}
+
+		freeze := lastFreeze.IsZero() || t.Sub(lastFreeze) >= FreezeCooldown*24*time.Hour
+		switch {
+		case day.Met():
+			streak++
+		case date == today:
+			// Still time to reach today's goal
+		case streak > 0 && freeze:
+			lastFreeze = t
+			frozen = append(frozen, date)
+		default:
+			streak = 0
+			frozen = nil
+		}
+		if streak > s.Longest {
+			s.Longest = streak
+		}
+	}
+
+	s.Current = streak
+	s.FrozenDays = frozen
+	s.FreezeAvailable = lastFreeze.IsZero() || end.Sub(lastFreeze) >= FreezeCooldown*24*time.Hour
+	return s
+}
+
+// HeatmapCell is one square of the activity calendar. Level runs from 0 (no
+// activity) to 4 (double the goal or more).
+type HeatmapCell struct {
+	Date   string
+	XP     int64
+	Level  int
+	Future bool
+}
+
+// Heatmap lays out the last weeks of activity GitHub-style: one column per
+// week, Sunday at the top, with today in the final column.
+func Heatmap(days []Day, today string, weeks int) [][]HeatmapCell {
+	end, err := time.Parse(DateLayout, today)
+	if err != nil {
+		return nil
+	}
+	byDate := make(map[string]Day, len(days))
+	for _, d := range days {
+		byDate[d.Date] = d
+	}
+
+	// Start on the Sunday weeks-1 weeks before this week's Sunday
+	start := end.AddDate(0, 0, -int(end.Weekday())-7*(weeks-1))
+
+	columns := make([][]HeatmapCell, weeks)
+	for w := range columns {
+		columns[w] = make([]HeatmapCell, 7)
+		for d := range columns[w] {
+			t := start.AddDate(0, 0, w*7+d)
+			date := t.Format(DateLayout)
+			day := byDate[date]
+			columns[w][d] = HeatmapCell{
+				Date:   date,
+				XP:     day.XP,
+				Level:  level(day),
+				Future: t.After(end),
+			}
+		}
+	}
+	return columns
+}
+
+func level(d Day) int {
+	switch {
+	case d.XP <= 0:
+		return 0
+	case d.Goal <= 0 || d.XP < d.Goal/2:
+		return 1
+	case d.XP < d.Goal:
+		return 2
+	case d.XP < 2*d.Goal:
+		return 3
+	}
+	return 4
+}
@@ -0,0 +1,404 @@
+// Package anki builds Anki deck packages (.apkg) from lesson vocabulary.
+//
+// An .apkg is a zip holding collection.anki2, a SQLite database in Anki's
+// schema version 11, plus a "media" JSON index mapping numbered zip entries
+// to their original filenames.
+package anki
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Note is one vocabulary item. Anki generates two cards from it: recognise
+// (target → English) and recall (English → target).
+type Note struct {
+	GUID           string // stable across exports so re-importing updates notes
+	Deck           string // full deck name, levels separated by "::"
+	English        string
+	Target         string
+	TargetAlt      string
+	Pronunciation  string
+	Example        string
+	ExampleEnglish string
+	Audio          string // media filename, or "" for none
+	Tags           []string
+}
+
+// Package is a set of notes and the media files they reference.
+type Package struct {
+	Notes []Note
+	Media map[string][]byte
+}
+
+// fieldNames are the note type's fields, in the order Note fields are stored.
+var fieldNames = []string{
+	"English", "Target", "TargetAlt", "Pronunciation", "Example", "ExampleEnglish", "Audio",
+}
+
+func (n Note) fields() []string {
+	audio := ""
+	if n.Audio != "" {
+		audio = "[sound:" + n.Audio + "]"
+	}
+	return []string{
+		html.EscapeString(n.English),
+		html.EscapeString(n.Target),
+		html.EscapeString(n.TargetAlt),
+		html.EscapeString(n.Pronunciation),
+		html.EscapeString(n.Example),
+		html.EscapeString(n.ExampleEnglish),
+		audio,
+	}
+}
+
+const modelName = "SpeakEasy Vocabulary"
+
+const frontTarget = `<div class="target">{{Target}}</div>
+{{#TargetAlt}}<div class="alt">{{TargetAlt}}</div>{{/TargetAlt}}
+{{Audio}}`
+
+const backTarget = `{{FrontSide}}
+<hr id="answer">
+<div class="english">{{English}}</div>
+{{#Pronunciation}}<div class="hint">{{Pronunciation}}</div>{{/Pronunciation}}
+{{#Example}}<div class="example">{{Example}}<br><span class="hint">{{ExampleEnglish}}</span></div>{{/Example}}`
+
+const frontEnglish = `<div class="english">{{English}}</div>`
+
+const backEnglish = `{{FrontSide}}
+<hr id="answer">
+<div class="target">{{Target}}</div>
+{{#TargetAlt}}<div class="alt">{{TargetAlt}}</div>{{/TargetAlt}}
+{{#Pronunciation}}<div class="hint">{{Pronunciation}}</div>{{/Pronunciation}}
+{{Audio}}
+{{#Example}}<div class="example">{{Example}}<br><span class="hint">{{ExampleEnglish}}</span></div>{{/Example}}`
+
+const cardCSS = `.card { font-family: Arial, sans-serif; font-size: 22px; text-align: center; color: #111827; background: white; }
+.target { font-size: 32px; font-weight: bold; color: #5B21B6; }
+.alt { font-size: 24px; color: #7C3AED; }
+.english { font-size: 28px; }
+.hint { font-size: 16px; color: #6B7280; font-style: italic; }
+.example { margin-top: 1em; font-size: 18px; }`
+
+const schema = `
+CREATE TABLE col (
+    id integer primary key, crt integer not null, mod integer not null,
+    scm integer not null, ver integer not null, dty integer not null,
+    usn integer not null, ls integer not null, conf text not null,
+    models text not null, decks text not null, dconf text not null,
+    tags text not null
+);
+CREATE TABLE notes (
+    id integer primary key, guid text not null, mid integer not null,
+    mod integer not null, usn integer not null, tags text not null,
+    flds text not null, sfld integer not null, csum integer not null,
+    flags integer not null, data text not null
+);
+CREATE TABLE cards (
+    id integer primary key, nid integer not null, did integer not null,
+    ord integer not null, mod integer not null, usn integer not null,
+    type integer not null, queue integer not null, due integer not null,
+    ivl integer not null, factor integer not null, reps integer not null,
+    lapses integer not null, left integer not null, odue integer not null,
+    odid integer not null, flags integer not null, data text not null
+);
+CREATE TABLE revlog (
+    id integer primary key, cid integer not null, usn integer not null,
+    ease integer not null, ivl integer not null, lastIvl integer not null,
+    factor integer not null, time integer not null, type integer not null
+);
+CREATE TABLE graves (
+    usn integer not null, oid integer not null, type integer not null
+);
+CREATE INDEX ix_notes_usn ON notes (usn);
+CREATE INDEX ix_cards_usn ON cards (usn);
+CREATE INDEX ix_revlog_usn ON revlog (usn);
+CREATE INDEX ix_cards_nid ON cards (nid);
+CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+CREATE INDEX ix_revlog_cid ON revlog (cid);
+CREATE INDEX ix_notes_csum ON notes (csum);
+`
+
+// WriteAPKG writes the package as an .apkg zip to w.
+func (p *Package) WriteAPKG(w io.Writer) error {
+	dir, err := os.MkdirTemp("", "speakeasy-anki-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	collection := filepath.Join(dir, "collection.anki2")
+	if err := p.writeCollection(collection); err != nil {
+		return fmt.Errorf("build collection: %w", err)
+	}
+	data, err := os.ReadFile(collection)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	if err := writeZipFile(zw, "collection.anki2", data); err != nil {
+		return err
+	}
+
+	// Media entries are stored as "0", "1", ... and named by the index
+	names := make([]string, 0, len(p.Media))
+	for name := range p.Media {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	index := make(map[string]string, len(names))
+	for i, name := range names {
+		key := strconv.Itoa(i)
+		index[key] = name
+		if err := writeZipFile(zw, key, p.Media[name]); err != nil {
+			return err
+		}
+	}
+	mediaJSON, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := writeZipFile(zw, "media", mediaJSON); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+func (p *Package) writeCollection(path string) error {
+	ctx := context.Background()
+	database, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, schema); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	nowMS := now.UnixMilli()
+	modelID := stableID(modelName)
+
+	deckIDs := make(map[string]int64)
+	for _, n := range p.Notes {
+		for _, name := range deckPath(n.Deck) {
+			deckIDs[name] = stableID("deck:" + name)
+		}
+	}
+
+	models, decks, dconf, conf, err := collectionJSON(modelID, deckIDs, now)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO col (id, crt, mod, scm, ver, dty, usn, ls, conf, models, decks, dconf, tags)
+		 VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		now.Truncate(24*time.Hour).Unix(), nowMS, nowMS, conf, models, decks, dconf,
+	); err != nil {
+		return err
+	}
+
+	// Note and card IDs only need to be unique within the package; Anki
+	// matches notes on GUID when importing into an existing collection.
+	nextID := nowMS
+	for i, n := range p.Notes {
+		noteID := nextID
+		nextID++
+		flds := n.fields()
+		sfld := sortField(flds[0])
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO notes (id, guid, mid, mod, usn, tags, flds, sfld, csum, flags, data)
+			 VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')`,
+			noteID, n.GUID, modelID, now.Unix(), noteTags(n.Tags),
+			strings.Join(flds, "\x1f"), sfld, checksum(sfld),
+		); err != nil {
+			return fmt.Errorf("note %q: %w", n.GUID, err)
+		}
+		for ord := 0; ord < 2; ord++ {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO cards (id, nid, did, ord, mod, usn, type, queue, due, ivl, factor, reps, lapses, left, odue, odid, flags, data)
+				 VALUES (?, ?, ?, ?, ?, -1, 0, 0, ?, 0, 0, 0, 0, 0, 0, 0, 0, '')`,
+				nextID, noteID, deckIDs[n.Deck], ord, now.Unix(), i+1,
+			); err != nil {
+				return fmt.Errorf("card for %q: %w", n.GUID, err)
+			}
+			nextID++
+		}
+	}
+
+	return tx.Commit()
+}
+
+// collectionJSON builds the JSON blobs stored in the col row.
+func collectionJSON(modelID int64, deckIDs map[string]int64, now time.Time) (models, decks, dconf, conf string, err error) {
+	var flds []map[string]interface{}
+	for i, name := range fieldNames {
+		flds = append(flds, map[string]interface{}{
+			"name": name, "ord": i, "sticky": false, "rtl": false,
+			"font": "Arial", "size": 20, "media": []string{},
+		})
+	}
+	tmpl := func(ord int, name, qfmt, afmt string) map[string]interface{} {
+		return map[string]interface{}{
+			"name": name, "ord": ord, "qfmt": qfmt, "afmt": afmt,
+			"did": nil, "bqfmt": "", "bafmt": "",
+		}
+	}
+	model := map[string]interface{}{
+		"id":    modelID,
+		"name":  modelName,
+		"type":  0,
+		"mod":   now.Unix(),
+		"usn":   -1,
+		"sortf": 0,
+		"did":   1,
+		"flds":  flds,
+		"tmpls": []map[string]interface{}{
+			tmpl(0, "Recognise", frontTarget, backTarget),
+			tmpl(1, "Recall", frontEnglish, backEnglish),
+		},
+		"css":       cardCSS,
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+		"tags":      []string{},
+		"vers":      []int{},
+		// Recognise needs Target (field 1), Recall needs English (field 0)
+		"req": []interface{}{
+			[]interface{}{0, "any", []int{1}},
+			[]interface{}{1, "any", []int{0}},
+		},
+	}
+
+	deck := func(id int64, name string) map[string]interface{} {
+		return map[string]interface{}{
+			"id": id, "name": name, "mod": now.Unix(), "usn": -1, "desc": "",
+			"dyn": 0, "conf": 1, "collapsed": false, "browserCollapsed": false,
+			"extendNew": 10, "extendRev": 50,
+			"newToday": []int{0, 0}, "revToday": []int{0, 0},
+			"lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+		}
+	}
+	deckMap := map[string]interface{}{"1": deck(1, "Default")}
+	for name, id := range deckIDs {
+		deckMap[strconv.FormatInt(id, 10)] = deck(id, name)
+	}
+
+	dconfMap := map[string]interface{}{
+		"1": map[string]interface{}{
+			"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60,
+			"autoplay": true, "timer": 0, "replayq": true, "dyn": false,
+			"new": map[string]interface{}{
+				"bury": false, "delays": []int{1, 10}, "initialFactor": 2500,
+				"ints": []int{1, 4, 0}, "order": 1, "perDay": 20,
+			},
+			"lapse": map[string]interface{}{
+				"delays": []int{10}, "leechAction": 1, "leechFails": 8, "minInt": 1, "mult": 0,
+			},
+			"rev": map[string]interface{}{
+				"bury": false, "ease4": 1.3, "ivlFct": 1, "maxIvl": 36500, "perDay": 200, "hardFactor": 1.2,
+			},
+		},
+	}
+
+	confMap := map[string]interface{}{
+		"activeDecks": []int{1}, "curDeck": 1, "newSpread": 0, "collapseTime": 1200,
+		"timeLim": 0, "estTimes": true, "dueCounts": true, "curModel": strconv.FormatInt(modelID, 10),
+		"nextPos": 1, "sortType": "noteFld", "sortBackwards": false, "addToCur": true,
+	}
+
+	out := make([]string, 4)
+	for i, v := range []interface{}{
+		map[string]interface{}{strconv.FormatInt(modelID, 10): model},
+		deckMap, dconfMap, confMap,
+	} {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", "", "", "", err
+		}
+		out[i] = string(b)
+	}
+	return out[0], out[1], out[2], out[3], nil
+}
+
+// deckPath returns a deck and all of its parents: "A::B" → ["A", "A::B"].
+func deckPath(name string) []string {
+	parts := strings.Split(name, "::")
+	result := make([]string, len(parts))
+	for i := range parts {
+		result[i] = strings.Join(parts[:i+1], "::")
+	}
+	return result
+}
+
+// noteTags formats tags the way Anki stores them: space separated with a
+// leading and trailing space.
+func noteTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	clean := make([]string, len(tags))
+	for i, t := range tags {
+		clean[i] = strings.ReplaceAll(t, " ", "_")
+	}
+	return " " + strings.Join(clean, " ") + " "
+}
+
+// sortField is a note's sort field as Anki stores it in sfld: the field's
+// text with HTML tags and [sound:] references removed and entities decoded.
+func sortField(field string) string {
+	return strings.TrimSpace(html.UnescapeString(markup.ReplaceAllString(field, "")))
+}
+
+var markup = regexp.MustCompile(`(?s)<[^>]*>|\[sound:[^\]]*\]`)
+
+// checksum is Anki's duplicate-detection hash: the first 8 hex digits of the
+// SHA-1 of the sort field.
+func checksum(s string) int64 {
+	h := sha1.Sum([]byte(s))
+	return int64(binary.BigEndian.Uint32(h[:4]))
+}
+
+// stableID derives a positive 47-bit ID from a name so the same model and
+// decks are reused when a learner imports an updated package.
+func stableID(name string) int64 {
+	h := sha1.Sum([]byte("speakeasy:" + name))
+	return int64(binary.BigEndian.Uint64(h[:8]) >> 17)
+}
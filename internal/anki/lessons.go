@@ -0,0 +1,74 @@
+package anki
+
+import (
+	"fmt"
+	"strings"
+
+	"speakeasy/internal/lessons"
+)
+
+// AudioSource supplies already-synthesized audio. Exports never call the TTS
+// API, so words that have not been played in the app yet have no audio.
+type AudioSource interface {
+	CachedAudio(text, lang, gender string) ([]byte, bool)
+}
+
+// LanguagePackage builds a package with one subdeck per published lesson
+// under a "SpeakEasy::<Language>" parent deck. audio may be nil.
+func LanguagePackage(lang *lessons.Language, audio AudioSource) *Package {
+	return LessonsPackage(lang, lessons.GetLessons(lang.Slug, false), audio)
+}
+
+// LessonsPackage builds a package from the given lessons of one language.
+// A word that appears in several lessons is added once, to its first lesson.
+func LessonsPackage(lang *lessons.Language, ls []*lessons.Lesson, audio AudioSource) *Package {
+	p := &Package{Media: make(map[string][]byte)}
+	seen := make(map[string]bool)
+	for _, l := range ls {
+		deck := fmt.Sprintf("SpeakEasy::%s::%02d %s", lang.DisplayName, l.Order, l.Title)
+		for _, section := range l.Sections {
+			for _, item := range section.Items {
+				if seen[item.ID] {
+					continue
+				}
+				seen[item.ID] = true
+				p.Notes = append(p.Notes, p.vocabNote(lang, l, deck, item, audio))
+			}
+		}
+	}
+	return p
+}
+
+func (p *Package) vocabNote(lang *lessons.Language, l *lessons.Lesson, deck string, item lessons.VocabItem, audio AudioSource) Note {
+	n := Note{
+		GUID:          "speakeasy-" + lang.Slug + "-" + item.ID,
+		Deck:          deck,
+		English:       item.English,
+		Target:        item.TargetPrimary,
+		TargetAlt:     item.TargetAlt,
+		Pronunciation: item.PronunciationHint,
+		Tags:          []string{"speakeasy", lang.Slug, l.ID},
+	}
+	if item.ExampleSentence != nil {
+		n.Example = item.ExampleSentence.TargetPrimary
+		n.ExampleEnglish = item.ExampleSentence.English
+	}
+	if audio != nil {
+		if data, ok := audio.CachedAudio(item.TargetPrimary, lang.TTSCode, ""); ok {
+			n.Audio = "speakeasy-" + lang.Slug + "-" + mediaSafe(item.ID) + ".mp3"
+			p.Media[n.Audio] = data
+		}
+	}
+	return n
+}
+
+// mediaSafe keeps media filenames to characters Anki accepts on every platform.
+func mediaSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -1,6 +1,9 @@
 package db
 
-import _ "embed"
+import "embed"
 
-//go:embed schema.sql
-var SchemaSQL string
+// migrationFS holds the numbered up-migrations. sqlc reads the same directory
+// (see sqlc.yaml), so the generated code always matches the merged schema.
+//
+//go:embed migrations/*.sql
+var migrationFS embed.FS
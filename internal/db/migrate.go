@@ -0,0 +1,174 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration is a single numbered up-migration embedded from migrations/.
+// Files are named NNNN_description.sql and applied in version order.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// MigrationStatus pairs a known migration with when it was applied, if ever.
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+)`
+
+// Migrations returns all embedded migrations sorted by version.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		num, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: name must be NNNN_description.sql", entry.Name())
+		}
+		version, err := strconv.Atoi(num)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version %q", entry.Name(), num)
+		}
+		if prev, dup := seen[version]; dup {
+			return nil, fmt.Errorf("migration %s: version %d already used by %s", entry.Name(), version, prev)
+		}
+		seen[version] = entry.Name()
+
+		data, err := fs.ReadFile(migrationFS, "migrations/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, Migration{Version: version, Name: name, SQL: string(data)})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+	return result, nil
+}
+
+// MigrationStatuses reports every embedded migration and whether it has been
+// applied. It returns an error if the database records a version this binary
+// does not know about, which means it was migrated by a newer build.
+func MigrationStatuses(ctx context.Context, database *sql.DB) ([]MigrationStatus, error) {
+	if _, err := database.ExecContext(ctx, createMigrationsTable); err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	all, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := database.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at sql.NullTime
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at.Time
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	known := make(map[int]bool, len(all))
+	result := make([]MigrationStatus, 0, len(all))
+	for _, m := range all {
+		known[m.Version] = true
+		at, ok := applied[m.Version]
+		result = append(result, MigrationStatus{Migration: m, Applied: ok, AppliedAt: at})
+	}
+	for version := range applied {
+		if !known[version] {
+			return nil, fmt.Errorf("database has migration %d applied but this binary does not know it; refusing to continue with an older build", version)
+		}
+	}
+	return result, nil
+}
+
+// PendingMigrations returns the migrations that have not yet been applied.
+func PendingMigrations(ctx context.Context, database *sql.DB) ([]Migration, error) {
+	statuses, err := MigrationStatuses(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, s := range statuses {
+		if !s.Applied {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate applies every pending migration, each in its own transaction, and
+// returns the ones it applied. A failed migration is rolled back and stops
+// the run so later migrations never see a half-applied schema.
+func Migrate(ctx context.Context, database *sql.DB) ([]Migration, error) {
+	pending, err := PendingMigrations(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, m := range pending {
+		if err := applyMigration(ctx, database, m); err != nil {
+			return applied, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+func applyMigration(ctx context.Context, database *sql.DB, m Migration) error {
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO schema_migrations (version, name) VALUES (?, ?)",
+		m.Version, m.Name,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
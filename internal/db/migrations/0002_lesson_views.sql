@@ -0,0 +1,9 @@
+-- Record when a lesson was first and last opened, separately from its status
+ALTER TABLE lesson_progress ADD COLUMN first_viewed DATETIME;
+ALTER TABLE lesson_progress ADD COLUMN last_viewed DATETIME;
+
+-- Lessons that were opened before this migration only have last_accessed
+UPDATE lesson_progress
+SET first_viewed = last_accessed,
+    last_viewed = last_accessed
+WHERE status IN ('in_progress', 'completed') AND last_accessed IS NOT NULL;
@@ -0,0 +1,24 @@
+-- A quiz taken one question at a time. Answers are saved as they are given
+-- so a refresh resumes mid-quiz; the session is finished when it is scored.
+CREATE TABLE quiz_sessions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    language TEXT NOT NULL,
+    lesson_id TEXT NOT NULL,
+    started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    finished_at DATETIME,
+    attempt_id INTEGER REFERENCES quiz_attempts(id)
+);
+
+CREATE INDEX idx_quiz_sessions_open ON quiz_sessions(user_id, language, lesson_id, finished_at);
+
+-- Each answer given in a quiz session, graded when it was submitted
+CREATE TABLE quiz_answers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id INTEGER NOT NULL REFERENCES quiz_sessions(id),
+    question_index INTEGER NOT NULL,
+    answer TEXT NOT NULL,
+    correct BOOLEAN NOT NULL,
+    answered_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(session_id, question_index)
+);
@@ -0,0 +1,9 @@
+-- Words a user has starred into their personal list
+CREATE TABLE starred_words (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    language TEXT NOT NULL,
+    word_id TEXT NOT NULL,
+    starred_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, language, word_id)
+);
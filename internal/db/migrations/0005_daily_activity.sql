@@ -0,0 +1,23 @@
+-- Per-user timezone and daily XP goal for streaks
+ALTER TABLE users ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+ALTER TABLE users ADD COLUMN daily_goal INTEGER NOT NULL DEFAULT 20;
+
+-- One row per user per day (YYYY-MM-DD in the user's timezone). goal is the
+-- user's goal on that day so changing it later doesn't rewrite old streaks.
+CREATE TABLE daily_activity (
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    day TEXT NOT NULL,
+    xp INTEGER NOT NULL DEFAULT 0,
+    quizzes INTEGER NOT NULL DEFAULT 0,
+    reviews INTEGER NOT NULL DEFAULT 0,
+    seconds INTEGER NOT NULL DEFAULT 0,
+    goal INTEGER NOT NULL,
+    PRIMARY KEY (user_id, day)
+);
+
+-- Credit past quiz attempts (UTC days; 10 XP plus a tenth of the score)
+INSERT INTO daily_activity (user_id, day, xp, quizzes, goal)
+SELECT user_id, substr(attempted_at, 1, 10), SUM(10 + score / 10), COUNT(*), 20
+FROM quiz_attempts
+WHERE attempted_at IS NOT NULL
+GROUP BY user_id, substr(attempted_at, 1, 10);
@@ -0,0 +1,8 @@
+-- Badges a user has earned. achievement_id refers to a rule declared in
+-- internal/achievements, so new badges need no schema change.
+CREATE TABLE user_achievements (
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    achievement_id TEXT NOT NULL,
+    earned_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, achievement_id)
+);
@@ -0,0 +1,20 @@
+-- Admins can reach /admin. Grant the first one with "speakeasy admin grant <username>".
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT 0;
+
+-- How each question of a graded quiz attempt went, for per-question
+-- difficulty. credit is the fraction answered correctly (partial for
+-- match_pairs); correct is set when credit is 1.
+CREATE TABLE question_results (
+    attempt_id INTEGER NOT NULL REFERENCES quiz_attempts(id),
+    question_index INTEGER NOT NULL,
+    credit REAL NOT NULL,
+    correct BOOLEAN NOT NULL,
+    PRIMARY KEY (attempt_id, question_index)
+);
+
+-- Carry over answers from one-question-at-a-time sessions that were scored
+INSERT INTO question_results (attempt_id, question_index, credit, correct)
+SELECT s.attempt_id, a.question_index, CASE WHEN a.correct THEN 1.0 ELSE 0.0 END, a.correct
+FROM quiz_answers a
+JOIN quiz_sessions s ON s.id = a.session_id
+WHERE s.attempt_id IS NOT NULL;
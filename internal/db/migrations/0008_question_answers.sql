@@ -0,0 +1,21 @@
+-- The answer given to each question, in the quiz form's encoding (an option
+-- index, typed text or match-pair JSON), for item analysis.
+ALTER TABLE question_results ADD COLUMN answer TEXT NOT NULL DEFAULT '';
+
+-- Carry over answers from step-by-step sessions; full-form answers given
+-- before this migration were never stored.
+UPDATE question_results
+SET answer = (
+    SELECT a.answer
+    FROM quiz_answers a
+    JOIN quiz_sessions s ON s.id = a.session_id
+    WHERE s.attempt_id = question_results.attempt_id
+      AND a.question_index = question_results.question_index
+)
+WHERE EXISTS (
+    SELECT 1
+    FROM quiz_answers a
+    JOIN quiz_sessions s ON s.id = a.session_id
+    WHERE s.attempt_id = question_results.attempt_id
+      AND a.question_index = question_results.question_index
+);
@@ -0,0 +1,16 @@
+-- Every version of a lesson saved from the admin lesson editor. published is
+-- false when the lesson's files are read-only (built-in languages outside
+-- development mode, zipped language packs): the revision is then a draft the
+-- editor opens again, to be downloaded and committed by hand. author is the
+-- username at the time, so history survives the account being deleted.
+CREATE TABLE lesson_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    language TEXT NOT NULL,
+    lesson_id TEXT NOT NULL,
+    content TEXT NOT NULL,
+    published BOOLEAN NOT NULL DEFAULT 0,
+    author TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_lesson_revisions_lesson ON lesson_revisions(language, lesson_id, id);
@@ -0,0 +1,8 @@
+-- The version (lessons.Lesson.Version) of the lesson a quiz was taken
+-- against, so question results can be matched to the questions they answered
+-- after the lesson is edited. Rows from before this migration have ''.
+ALTER TABLE quiz_attempts ADD COLUMN lesson_version TEXT NOT NULL DEFAULT '';
+
+-- A step-by-step quiz whose lesson changes part way through is started again
+-- rather than scored against different questions.
+ALTER TABLE quiz_sessions ADD COLUMN lesson_version TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+-- The seed a step-by-step quiz generated its questions from, for lessons
+-- whose quiz is partly generated from their vocabulary (quiz.generate), so
+-- every request of the session sees the same questions.
+ALTER TABLE quiz_sessions ADD COLUMN quiz_seed INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,19 @@
+-- Lessons skipped by a placement test count as completed, but are flagged so
+-- they can be told apart from lessons completed by passing their quiz.
+-- Passing the quiz later clears the flag.
+ALTER TABLE lesson_progress ADD COLUMN placed BOOLEAN NOT NULL DEFAULT 0;
+
+-- An adaptive placement test: one stage per lesson, in lesson order, each a
+-- few questions drawn by the seed. stage counts the stages passed so far; the
+-- test ends at the first stage failed or when every stage is passed.
+CREATE TABLE placement_tests (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    language TEXT NOT NULL,
+    seed INTEGER NOT NULL,
+    stage INTEGER NOT NULL DEFAULT 0,
+    started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    finished_at DATETIME
+);
+
+CREATE INDEX idx_placement_tests_open ON placement_tests(user_id, language, finished_at);
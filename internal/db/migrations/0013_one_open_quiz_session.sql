@@ -0,0 +1,15 @@
+-- A user has at most one unfinished quiz session per lesson, so two tabs or
+-- a double click can't start two and split the answers between them. Any
+-- duplicates already open are closed unscored, keeping the newest, which is
+-- the one the app has been answering.
+UPDATE quiz_sessions
+SET finished_at = CURRENT_TIMESTAMP
+WHERE finished_at IS NULL
+  AND id NOT IN (
+    SELECT MAX(id) FROM quiz_sessions
+    WHERE finished_at IS NULL
+    GROUP BY user_id, language, lesson_id
+  );
+
+CREATE UNIQUE INDEX idx_quiz_sessions_one_open ON quiz_sessions(user_id, language, lesson_id)
+WHERE finished_at IS NULL;
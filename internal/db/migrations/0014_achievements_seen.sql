@@ -0,0 +1,6 @@
+-- Whether the dashboard has shown a badge since it was earned, so it can
+-- announce badges earned elsewhere, say by a quiz, on the next visit. Badges
+-- earned before this were already announced.
+ALTER TABLE user_achievements ADD COLUMN seen BOOLEAN NOT NULL DEFAULT 0;
+
+UPDATE user_achievements SET seen = 1;
@@ -0,0 +1,14 @@
+-- The rank of each progress row's status, as lessons.Status.Rank orders
+-- statuses. Writes store the rank Go computes alongside the status, so the
+-- upserts can keep a lesson from moving backwards by comparing ranks without
+-- ranking statuses in SQL themselves. Existing rows are ranked here once.
+ALTER TABLE lesson_progress ADD COLUMN status_rank INTEGER NOT NULL DEFAULT -1;
+
+UPDATE lesson_progress
+SET status_rank = CASE status
+    WHEN 'locked' THEN 0
+    WHEN 'available' THEN 1
+    WHEN 'in_progress' THEN 2
+    WHEN 'completed' THEN 3
+    ELSE -1
+END;
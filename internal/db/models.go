@@ -8,6 +8,16 @@ import (
 	"database/sql"
 )
 
+type DailyActivity struct {
+	UserID  int64
+	Day     string
+	Xp      int64
+	Quizzes int64
+	Reviews int64
+	Seconds int64
+	Goal    int64
+}
+
 type LessonProgress struct {
 	ID           int64
 	UserID       int64
@@ -18,6 +28,47 @@ type LessonProgress struct {
 	Attempts     sql.NullInt64
 	LastAccessed sql.NullTime
 	CompletedAt  sql.NullTime
+	FirstViewed  sql.NullTime
+	LastViewed   sql.NullTime
+	Placed       bool
+	StatusRank   int64
+}
+
+type LessonRevision struct {
+	ID        int64
+	Language  string
+	LessonID  string
+	Content   string
+	Published bool
+	Author    string
+	CreatedAt sql.NullTime
+}
+
+type PlacementTest struct {
+	ID         int64
+	UserID     int64
+	Language   string
+	Seed       int64
+	Stage      int64
+	StartedAt  sql.NullTime
+	FinishedAt sql.NullTime
+}
+
+type QuestionResult struct {
+	AttemptID     int64
+	QuestionIndex int64
+	Credit        float64
+	Correct       bool
+	Answer        string
+}
+
+type QuizAnswer struct {
+	ID            int64
+	SessionID     int64
+	QuestionIndex int64
+	Answer        string
+	Correct       bool
+	AnsweredAt    sql.NullTime
 }
 
 type QuizAttempt struct {
@@ -29,6 +80,27 @@ type QuizAttempt struct {
 	TotalQuestions int64
 	CorrectAnswers int64
 	AttemptedAt    sql.NullTime
+	LessonVersion  string
+}
+
+type QuizSession struct {
+	ID            int64
+	UserID        int64
+	Language      string
+	LessonID      string
+	StartedAt     sql.NullTime
+	FinishedAt    sql.NullTime
+	AttemptID     sql.NullInt64
+	LessonVersion string
+	QuizSeed      int64
+}
+
+type StarredWord struct {
+	ID        int64
+	UserID    int64
+	Language  string
+	WordID    string
+	StarredAt sql.NullTime
 }
 
 type User struct {
@@ -38,6 +110,16 @@ type User struct {
 	PasswordHash string
 	DisplayName  string
 	CreatedAt    sql.NullTime
+	Timezone     string
+	DailyGoal    int64
+	IsAdmin      bool
+}
+
+type UserAchievement struct {
+	UserID        int64
+	AchievementID string
+	EarnedAt      sql.NullTime
+	Seen          bool
 }
 
 type VocabProgress struct {
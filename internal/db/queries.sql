@@ -22,20 +22,68 @@ WHERE user_id = ? AND language = ?
 ORDER BY lesson_id;
 
 -- name: UpsertLessonProgress :one
-INSERT INTO lesson_progress (user_id, language, lesson_id, status, best_score, attempts, last_accessed, completed_at)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+-- Status only moves forward (locked → available → in_progress → completed),
+-- by the status_rank the caller passes from lessons.Status.Rank; NULL values
+-- leave the existing columns untouched.
+INSERT INTO lesson_progress (user_id, language, lesson_id, status, status_rank, best_score, attempts, last_accessed, completed_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 ON CONFLICT(user_id, language, lesson_id)
 DO UPDATE SET
-    status = excluded.status,
-    best_score = CASE WHEN excluded.best_score > lesson_progress.best_score THEN excluded.best_score ELSE lesson_progress.best_score END,
-    attempts = excluded.attempts,
+    status = CASE WHEN excluded.status_rank > lesson_progress.status_rank THEN excluded.status ELSE lesson_progress.status END,
+    status_rank = MAX(excluded.status_rank, lesson_progress.status_rank),
+    best_score = CASE WHEN excluded.best_score > COALESCE(lesson_progress.best_score, 0) THEN excluded.best_score ELSE lesson_progress.best_score END,
+    attempts = COALESCE(excluded.attempts, lesson_progress.attempts),
+    last_accessed = COALESCE(excluded.last_accessed, lesson_progress.last_accessed),
+    completed_at = COALESCE(lesson_progress.completed_at, excluded.completed_at)
+RETURNING *;
+
+-- name: RecordLessonView :one
+INSERT INTO lesson_progress (user_id, language, lesson_id, status, status_rank, last_accessed, first_viewed, last_viewed)
+VALUES (sqlc.arg(user_id), sqlc.arg(language), sqlc.arg(lesson_id), sqlc.arg(status), sqlc.arg(status_rank), sqlc.arg(viewed_at), sqlc.arg(viewed_at), sqlc.arg(viewed_at))
+ON CONFLICT(user_id, language, lesson_id)
+DO UPDATE SET
+    status = CASE WHEN excluded.status_rank > lesson_progress.status_rank THEN excluded.status ELSE lesson_progress.status END,
+    status_rank = MAX(excluded.status_rank, lesson_progress.status_rank),
+    last_accessed = excluded.last_accessed,
+    first_viewed = COALESCE(lesson_progress.first_viewed, excluded.first_viewed),
+    last_viewed = excluded.last_viewed
+RETURNING *;
+
+-- name: RecordLessonAttempt :one
+-- Records a quiz attempt. Passing one turns a lesson completed by a
+-- placement test into an earned completion; failing it leaves it placed.
+INSERT INTO lesson_progress (user_id, language, lesson_id, status, status_rank, best_score, attempts, last_accessed, completed_at)
+VALUES (sqlc.arg(user_id), sqlc.arg(language), sqlc.arg(lesson_id), sqlc.arg(status), sqlc.arg(status_rank), sqlc.arg(best_score), 1, sqlc.arg(last_accessed), sqlc.arg(completed_at))
+ON CONFLICT(user_id, language, lesson_id)
+DO UPDATE SET
+    status = CASE WHEN excluded.status_rank > lesson_progress.status_rank THEN excluded.status ELSE lesson_progress.status END,
+    status_rank = MAX(excluded.status_rank, lesson_progress.status_rank),
+    best_score = CASE WHEN excluded.best_score > COALESCE(lesson_progress.best_score, 0) THEN excluded.best_score ELSE lesson_progress.best_score END,
+    attempts = COALESCE(lesson_progress.attempts, 0) + 1,
     last_accessed = excluded.last_accessed,
-    completed_at = COALESCE(excluded.completed_at, lesson_progress.completed_at)
+    completed_at = COALESCE(lesson_progress.completed_at, excluded.completed_at),
+    placed = CASE WHEN CAST(sqlc.arg(passed) AS BOOLEAN) THEN 0 ELSE lesson_progress.placed END
 RETURNING *;
 
+-- name: UnlockLesson :exec
+INSERT INTO lesson_progress (user_id, language, lesson_id, status, status_rank)
+VALUES (?, ?, ?, 'available', ?)
+ON CONFLICT(user_id, language, lesson_id)
+DO UPDATE SET status = 'available', status_rank = excluded.status_rank
+WHERE lesson_progress.status_rank < excluded.status_rank;
+
+-- name: PlaceLesson :exec
+-- Marks a lesson completed by a placement test. A lesson already completed
+-- keeps its earned completion.
+INSERT INTO lesson_progress (user_id, language, lesson_id, status, status_rank, completed_at, placed)
+VALUES (?, ?, ?, 'completed', ?, ?, 1)
+ON CONFLICT(user_id, language, lesson_id)
+DO UPDATE SET status = 'completed', status_rank = excluded.status_rank, completed_at = excluded.completed_at, placed = 1
+WHERE lesson_progress.status_rank < excluded.status_rank;
+
 -- name: CreateQuizAttempt :one
-INSERT INTO quiz_attempts (user_id, language, lesson_id, score, total_questions, correct_answers)
-VALUES (?, ?, ?, ?, ?, ?)
+INSERT INTO quiz_attempts (user_id, language, lesson_id, score, total_questions, correct_answers, lesson_version)
+VALUES (?, ?, ?, ?, ?, ?, ?)
 RETURNING *;
 
 -- name: ListQuizAttempts :many
@@ -66,6 +114,248 @@ WHERE user_id = ? AND language = ? AND word_id = ?;
 SELECT COUNT(*) FROM lesson_progress
 WHERE user_id = ? AND language = ? AND status = 'completed';
 
+-- name: CountEarnedLessons :one
+-- Completed lessons, leaving out those completed by a placement test.
+SELECT COUNT(*) FROM lesson_progress
+WHERE user_id = ? AND language = ? AND status = 'completed' AND placed = 0;
+
 -- name: GetTotalScore :one
 SELECT COALESCE(SUM(best_score), 0) FROM lesson_progress
 WHERE user_id = ? AND language = ?;
+
+-- name: CreateQuizSession :one
+-- Starts a quiz session unless one is already open for the lesson, in which
+-- case it returns no row.
+INSERT INTO quiz_sessions (user_id, language, lesson_id, lesson_version, quiz_seed)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (user_id, language, lesson_id) WHERE finished_at IS NULL DO NOTHING
+RETURNING *;
+
+-- name: GetOpenQuizSession :one
+SELECT * FROM quiz_sessions
+WHERE user_id = ? AND language = ? AND lesson_id = ? AND finished_at IS NULL
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: FinishQuizSession :execrows
+UPDATE quiz_sessions
+SET finished_at = ?, attempt_id = ?
+WHERE id = ? AND finished_at IS NULL;
+
+-- name: CreatePlacementTest :one
+INSERT INTO placement_tests (user_id, language, seed)
+VALUES (?, ?, ?)
+RETURNING *;
+
+-- name: GetOpenPlacementTest :one
+SELECT * FROM placement_tests
+WHERE user_id = ? AND language = ? AND finished_at IS NULL
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: AdvancePlacementTest :execrows
+-- Moves past a passed stage; a resubmitted stage changes nothing.
+UPDATE placement_tests
+SET stage = stage + 1
+WHERE id = ? AND stage = ? AND finished_at IS NULL;
+
+-- name: FinishPlacementTest :execrows
+UPDATE placement_tests
+SET finished_at = ?
+WHERE id = ? AND finished_at IS NULL;
+
+-- name: SaveQuizAnswer :exec
+-- The first answer to a question stands; resubmitting it is a no-op.
+INSERT INTO quiz_answers (session_id, question_index, answer, correct)
+VALUES (?, ?, ?, ?)
+ON CONFLICT(session_id, question_index) DO NOTHING;
+
+-- name: ListQuizAnswers :many
+SELECT * FROM quiz_answers
+WHERE session_id = ?
+ORDER BY question_index;
+
+-- name: StarWord :exec
+INSERT INTO starred_words (user_id, language, word_id)
+VALUES (?, ?, ?)
+ON CONFLICT(user_id, language, word_id) DO NOTHING;
+
+-- name: UnstarWord :exec
+DELETE FROM starred_words
+WHERE user_id = ? AND language = ? AND word_id = ?;
+
+-- name: ListStarredWords :many
+SELECT * FROM starred_words
+WHERE user_id = ? AND language = ?
+ORDER BY starred_at;
+
+-- name: UpdateUserGoal :exec
+UPDATE users SET daily_goal = ? WHERE id = ?;
+
+-- name: UpdateUserTimezone :exec
+UPDATE users SET timezone = ? WHERE id = ?;
+
+-- name: AddDailyActivity :exec
+-- Adds to the day's totals, creating the row on the first activity of the day.
+INSERT INTO daily_activity (user_id, day, xp, quizzes, reviews, seconds, goal)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(user_id, day) DO UPDATE SET
+    xp = xp + excluded.xp,
+    quizzes = quizzes + excluded.quizzes,
+    reviews = reviews + excluded.reviews,
+    seconds = seconds + excluded.seconds,
+    goal = excluded.goal;
+
+-- name: ListDailyActivity :many
+SELECT * FROM daily_activity
+WHERE user_id = ?
+ORDER BY day;
+
+-- name: AwardAchievement :execrows
+INSERT INTO user_achievements (user_id, achievement_id)
+VALUES (?, ?)
+ON CONFLICT(user_id, achievement_id) DO NOTHING;
+
+-- name: ListUserAchievements :many
+SELECT * FROM user_achievements
+WHERE user_id = ?
+ORDER BY earned_at, achievement_id;
+
+-- name: MarkAchievementsSeen :exec
+UPDATE user_achievements SET seen = 1
+WHERE user_id = ? AND seen = 0;
+
+-- name: CountPerfectQuizzes :one
+SELECT COUNT(*) FROM quiz_attempts
+WHERE user_id = ? AND score >= 100;
+
+-- name: CountMasteredWords :one
+SELECT COUNT(*) FROM vocab_progress
+WHERE user_id = ? AND mastery_level >= ?;
+
+-- name: SaveQuestionResult :exec
+INSERT INTO question_results (attempt_id, question_index, credit, correct, answer)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: ListQuestionResponses :many
+-- Every graded answer across all users with its attempt's total score.
+SELECT qa.id AS attempt_id, qa.language, qa.lesson_id, qa.lesson_version, qa.score,
+    qr.question_index, qr.credit, qr.correct, qr.answer
+FROM question_results qr
+JOIN quiz_attempts qa ON qa.id = qr.attempt_id
+ORDER BY qa.language, qa.lesson_id, qa.id, qr.question_index;
+
+-- name: ListUserSummaries :many
+SELECT u.id, u.username, u.email, u.display_name, u.created_at, u.is_admin,
+    (SELECT COUNT(*) FROM lesson_progress lp WHERE lp.user_id = u.id AND lp.status = 'completed') AS lessons_completed,
+    (SELECT COUNT(*) FROM quiz_attempts qa WHERE qa.user_id = u.id) AS quiz_attempts,
+    (SELECT COUNT(*) FROM vocab_progress vp WHERE vp.user_id = u.id) AS words_seen,
+    CAST(COALESCE((SELECT MAX(da.day) FROM daily_activity da WHERE da.user_id = u.id), '') AS TEXT) AS last_active
+FROM users u
+ORDER BY u.created_at DESC, u.id DESC;
+
+-- name: UpdateUserPassword :exec
+UPDATE users SET password_hash = ? WHERE id = ?;
+
+-- name: SetUserAdmin :exec
+UPDATE users SET is_admin = ? WHERE id = ?;
+
+-- name: DeleteUserQuestionResults :exec
+DELETE FROM question_results
+WHERE attempt_id IN (SELECT id FROM quiz_attempts WHERE user_id = ?);
+
+-- name: DeleteUserQuizAnswers :exec
+DELETE FROM quiz_answers
+WHERE session_id IN (SELECT id FROM quiz_sessions WHERE user_id = ?);
+
+-- name: DeleteUserQuizSessions :exec
+DELETE FROM quiz_sessions WHERE user_id = ?;
+
+-- name: DeleteUserQuizAttempts :exec
+DELETE FROM quiz_attempts WHERE user_id = ?;
+
+-- name: DeleteUserPlacementTests :exec
+DELETE FROM placement_tests WHERE user_id = ?;
+
+-- name: DeleteUserLessonProgress :exec
+DELETE FROM lesson_progress WHERE user_id = ?;
+
+-- name: DeleteUserVocabProgress :exec
+DELETE FROM vocab_progress WHERE user_id = ?;
+
+-- name: DeleteUserDailyActivity :exec
+DELETE FROM daily_activity WHERE user_id = ?;
+
+-- name: DeleteUserAchievements :exec
+DELETE FROM user_achievements WHERE user_id = ?;
+
+-- name: DeleteUserStarredWords :exec
+DELETE FROM starred_words WHERE user_id = ?;
+
+-- name: ListSavedWordIDs :many
+-- Every word id that has vocabulary progress or a star, with how many users
+-- have each.
+SELECT language, word_id,
+    CAST(SUM(progress) AS INTEGER) AS progress_users,
+    CAST(SUM(starred) AS INTEGER) AS starred_users
+FROM (
+    SELECT language, word_id, 1 AS progress, 0 AS starred FROM vocab_progress
+    UNION ALL
+    SELECT language, word_id, 0 AS progress, 1 AS starred FROM starred_words
+)
+GROUP BY language, word_id
+ORDER BY language, word_id;
+
+-- name: MoveVocabProgress :exec
+-- Users who already have progress on the new word id keep it; their rows for
+-- the old id are left for DeleteWordVocabProgress.
+UPDATE OR IGNORE vocab_progress SET word_id = sqlc.arg(new_word_id)
+WHERE language = sqlc.arg(language) AND word_id = sqlc.arg(old_word_id);
+
+-- name: MoveStarredWord :exec
+UPDATE OR IGNORE starred_words SET word_id = sqlc.arg(new_word_id)
+WHERE language = sqlc.arg(language) AND word_id = sqlc.arg(old_word_id);
+
+-- name: DeleteWordVocabProgress :exec
+DELETE FROM vocab_progress WHERE language = ? AND word_id = ?;
+
+-- name: DeleteWordStars :exec
+DELETE FROM starred_words WHERE language = ? AND word_id = ?;
+
+-- name: DeleteUser :exec
+DELETE FROM users WHERE id = ?;
+
+-- name: CreateLessonRevision :one
+INSERT INTO lesson_revisions (language, lesson_id, content, published, author)
+VALUES (?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: DeleteLessonRevision :exec
+-- Withdraws a revision whose content could not be published after all.
+DELETE FROM lesson_revisions WHERE id = ?;
+
+-- name: GetLessonRevision :one
+SELECT * FROM lesson_revisions WHERE id = ?;
+
+-- name: GetLatestLessonRevision :one
+SELECT * FROM lesson_revisions
+WHERE language = ? AND lesson_id = ?
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: ListLessonRevisions :many
+SELECT * FROM lesson_revisions
+WHERE language = ? AND lesson_id = ?
+ORDER BY id DESC
+LIMIT 50;
+
+-- name: ListLessonDrafts :many
+-- Lessons whose latest revision is an unpublished draft.
+SELECT r.id, r.language, r.lesson_id, r.content, r.published, r.author, r.created_at
+FROM lesson_revisions r
+WHERE r.published = 0
+  AND r.id = (
+    SELECT MAX(id) FROM lesson_revisions
+    WHERE language = r.language AND lesson_id = r.lesson_id
+  )
+ORDER BY r.language, r.lesson_id;
@@ -10,6 +10,80 @@ import (
 	"database/sql"
 )
 
+const addDailyActivity = `-- name: AddDailyActivity :exec
+INSERT INTO daily_activity (user_id, day, xp, quizzes, reviews, seconds, goal)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(user_id, day) DO UPDATE SET
+    xp = xp + excluded.xp,
+    quizzes = quizzes + excluded.quizzes,
+    reviews = reviews + excluded.reviews,
+    seconds = seconds + excluded.seconds,
+    goal = excluded.goal
+`
+
+type AddDailyActivityParams struct {
+	UserID  int64
+	Day     string
+	Xp      int64
+	Quizzes int64
+	Reviews int64
+	Seconds int64
+	Goal    int64
+}
+
+// Adds to the day's totals, creating the row on the first activity of the day.
+func (q *Queries) AddDailyActivity(ctx context.Context, arg AddDailyActivityParams) error {
+	_, err := q.db.ExecContext(ctx, addDailyActivity,
+		arg.UserID,
+		arg.Day,
+		arg.Xp,
+		arg.Quizzes,
+		arg.Reviews,
+		arg.Seconds,
+		arg.Goal,
+	)
+	return err
+}
+
+const advancePlacementTest = `-- name: AdvancePlacementTest :execrows
+UPDATE placement_tests
+SET stage = stage + 1
+WHERE id = ? AND stage = ? AND finished_at IS NULL
+`
+
+type AdvancePlacementTestParams struct {
+	ID    int64
+	Stage int64
+}
+
+// Moves past a passed stage; a resubmitted stage changes nothing.
+func (q *Queries) AdvancePlacementTest(ctx context.Context, arg AdvancePlacementTestParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, advancePlacementTest, arg.ID, arg.Stage)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const awardAchievement = `-- name: AwardAchievement :execrows
+INSERT INTO user_achievements (user_id, achievement_id)
+VALUES (?, ?)
+ON CONFLICT(user_id, achievement_id) DO NOTHING
+`
+
+type AwardAchievementParams struct {
+	UserID        int64
+	AchievementID string
+}
+
+func (q *Queries) AwardAchievement(ctx context.Context, arg AwardAchievementParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, awardAchievement, arg.UserID, arg.AchievementID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const countCompletedLessons = `-- name: CountCompletedLessons :one
 SELECT COUNT(*) FROM lesson_progress
 WHERE user_id = ? AND language = ? AND status = 'completed'
@@ -27,10 +101,119 @@ func (q *Queries) CountCompletedLessons(ctx context.Context, arg CountCompletedL
 	return count, err
 }
 
+const countEarnedLessons = `-- name: CountEarnedLessons :one
+SELECT COUNT(*) FROM lesson_progress
+WHERE user_id = ? AND language = ? AND status = 'completed' AND placed = 0
+`
+
+type CountEarnedLessonsParams struct {
+	UserID   int64
+	Language string
+}
+
+// Completed lessons, leaving out those completed by a placement test.
+func (q *Queries) CountEarnedLessons(ctx context.Context, arg CountEarnedLessonsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countEarnedLessons, arg.UserID, arg.Language)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countMasteredWords = `-- name: CountMasteredWords :one
+SELECT COUNT(*) FROM vocab_progress
+WHERE user_id = ? AND mastery_level >= ?
+`
+
+type CountMasteredWordsParams struct {
+	UserID       int64
+	MasteryLevel sql.NullInt64
+}
+
+func (q *Queries) CountMasteredWords(ctx context.Context, arg CountMasteredWordsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countMasteredWords, arg.UserID, arg.MasteryLevel)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countPerfectQuizzes = `-- name: CountPerfectQuizzes :one
+SELECT COUNT(*) FROM quiz_attempts
+WHERE user_id = ? AND score >= 100
+`
+
+func (q *Queries) CountPerfectQuizzes(ctx context.Context, userID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPerfectQuizzes, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createLessonRevision = `-- name: CreateLessonRevision :one
+INSERT INTO lesson_revisions (language, lesson_id, content, published, author)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id, language, lesson_id, content, published, author, created_at
+`
+
+type CreateLessonRevisionParams struct {
+	Language  string
+	LessonID  string
+	Content   string
+	Published bool
+	Author    string
+}
+
+func (q *Queries) CreateLessonRevision(ctx context.Context, arg CreateLessonRevisionParams) (LessonRevision, error) {
+	row := q.db.QueryRowContext(ctx, createLessonRevision,
+		arg.Language,
+		arg.LessonID,
+		arg.Content,
+		arg.Published,
+		arg.Author,
+	)
+	var i LessonRevision
+	err := row.Scan(
+		&i.ID,
+		&i.Language,
+		&i.LessonID,
+		&i.Content,
+		&i.Published,
+		&i.Author,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createPlacementTest = `-- name: CreatePlacementTest :one
+INSERT INTO placement_tests (user_id, language, seed)
+VALUES (?, ?, ?)
+RETURNING id, user_id, language, seed, stage, started_at, finished_at
+`
+
+type CreatePlacementTestParams struct {
+	UserID   int64
+	Language string
+	Seed     int64
+}
+
+func (q *Queries) CreatePlacementTest(ctx context.Context, arg CreatePlacementTestParams) (PlacementTest, error) {
+	row := q.db.QueryRowContext(ctx, createPlacementTest, arg.UserID, arg.Language, arg.Seed)
+	var i PlacementTest
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Language,
+		&i.Seed,
+		&i.Stage,
+		&i.StartedAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
 const createQuizAttempt = `-- name: CreateQuizAttempt :one
-INSERT INTO quiz_attempts (user_id, language, lesson_id, score, total_questions, correct_answers)
-VALUES (?, ?, ?, ?, ?, ?)
-RETURNING id, user_id, language, lesson_id, score, total_questions, correct_answers, attempted_at
+INSERT INTO quiz_attempts (user_id, language, lesson_id, score, total_questions, correct_answers, lesson_version)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+RETURNING id, user_id, language, lesson_id, score, total_questions, correct_answers, attempted_at, lesson_version
 `
 
 type CreateQuizAttemptParams struct {
@@ -40,6 +223,7 @@ type CreateQuizAttemptParams struct {
 	Score          int64
 	TotalQuestions int64
 	CorrectAnswers int64
+	LessonVersion  string
 }
 
 func (q *Queries) CreateQuizAttempt(ctx context.Context, arg CreateQuizAttemptParams) (QuizAttempt, error) {
@@ -50,6 +234,7 @@ func (q *Queries) CreateQuizAttempt(ctx context.Context, arg CreateQuizAttemptPa
 		arg.Score,
 		arg.TotalQuestions,
 		arg.CorrectAnswers,
+		arg.LessonVersion,
 	)
 	var i QuizAttempt
 	err := row.Scan(
@@ -61,6 +246,47 @@ func (q *Queries) CreateQuizAttempt(ctx context.Context, arg CreateQuizAttemptPa
 		&i.TotalQuestions,
 		&i.CorrectAnswers,
 		&i.AttemptedAt,
+		&i.LessonVersion,
+	)
+	return i, err
+}
+
+const createQuizSession = `-- name: CreateQuizSession :one
+INSERT INTO quiz_sessions (user_id, language, lesson_id, lesson_version, quiz_seed)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (user_id, language, lesson_id) WHERE finished_at IS NULL DO NOTHING
+RETURNING id, user_id, language, lesson_id, started_at, finished_at, attempt_id, lesson_version, quiz_seed
+`
+
+type CreateQuizSessionParams struct {
+	UserID        int64
+	Language      string
+	LessonID      string
+	LessonVersion string
+	QuizSeed      int64
+}
+
+// Starts a quiz session unless one is already open for the lesson, in which
+// case it returns no row.
+func (q *Queries) CreateQuizSession(ctx context.Context, arg CreateQuizSessionParams) (QuizSession, error) {
+	row := q.db.QueryRowContext(ctx, createQuizSession,
+		arg.UserID,
+		arg.Language,
+		arg.LessonID,
+		arg.LessonVersion,
+		arg.QuizSeed,
+	)
+	var i QuizSession
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Language,
+		&i.LessonID,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.AttemptID,
+		&i.LessonVersion,
+		&i.QuizSeed,
 	)
 	return i, err
 }
@@ -68,7 +294,7 @@ func (q *Queries) CreateQuizAttempt(ctx context.Context, arg CreateQuizAttemptPa
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (username, email, password_hash, display_name)
 VALUES (?, ?, ?, ?)
-RETURNING id, username, email, password_hash, display_name, created_at
+RETURNING id, username, email, password_hash, display_name, created_at, timezone, daily_goal, is_admin
 `
 
 type CreateUserParams struct {
@@ -93,12 +319,220 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.PasswordHash,
 		&i.DisplayName,
 		&i.CreatedAt,
+		&i.Timezone,
+		&i.DailyGoal,
+		&i.IsAdmin,
+	)
+	return i, err
+}
+
+const deleteLessonRevision = `-- name: DeleteLessonRevision :exec
+DELETE FROM lesson_revisions WHERE id = ?
+`
+
+// Withdraws a revision whose content could not be published after all.
+func (q *Queries) DeleteLessonRevision(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteLessonRevision, id)
+	return err
+}
+
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users WHERE id = ?
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
+const deleteUserAchievements = `-- name: DeleteUserAchievements :exec
+DELETE FROM user_achievements WHERE user_id = ?
+`
+
+func (q *Queries) DeleteUserAchievements(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteUserAchievements, userID)
+	return err
+}
+
+const deleteUserDailyActivity = `-- name: DeleteUserDailyActivity :exec
+DELETE FROM daily_activity WHERE user_id = ?
+`
+
+func (q *Queries) DeleteUserDailyActivity(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteUserDailyActivity, userID)
+	return err
+}
+
+const deleteUserLessonProgress = `-- name: DeleteUserLessonProgress :exec
+DELETE FROM lesson_progress WHERE user_id = ?
+`
+
+func (q *Queries) DeleteUserLessonProgress(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteUserLessonProgress, userID)
+	return err
+}
+
+const deleteUserPlacementTests = `-- name: DeleteUserPlacementTests :exec
+DELETE FROM placement_tests WHERE user_id = ?
+`
+
+func (q *Queries) DeleteUserPlacementTests(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteUserPlacementTests, userID)
+	return err
+}
+
+const deleteUserQuestionResults = `-- name: DeleteUserQuestionResults :exec
+DELETE FROM question_results
+WHERE attempt_id IN (SELECT id FROM quiz_attempts WHERE user_id = ?)
+`
+
+func (q *Queries) DeleteUserQuestionResults(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteUserQuestionResults, userID)
+	return err
+}
+
+const deleteUserQuizAnswers = `-- name: DeleteUserQuizAnswers :exec
+DELETE FROM quiz_answers
+WHERE session_id IN (SELECT id FROM quiz_sessions WHERE user_id = ?)
+`
+
+func (q *Queries) DeleteUserQuizAnswers(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteUserQuizAnswers, userID)
+	return err
+}
+
+const deleteUserQuizAttempts = `-- name: DeleteUserQuizAttempts :exec
+DELETE FROM quiz_attempts WHERE user_id = ?
+`
+
+func (q *Queries) DeleteUserQuizAttempts(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteUserQuizAttempts, userID)
+	return err
+}
+
+const deleteUserQuizSessions = `-- name: DeleteUserQuizSessions :exec
+DELETE FROM quiz_sessions WHERE user_id = ?
+`
+
+func (q *Queries) DeleteUserQuizSessions(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteUserQuizSessions, userID)
+	return err
+}
+
+const deleteUserStarredWords = `-- name: DeleteUserStarredWords :exec
+DELETE FROM starred_words WHERE user_id = ?
+`
+
+func (q *Queries) DeleteUserStarredWords(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteUserStarredWords, userID)
+	return err
+}
+
+const deleteUserVocabProgress = `-- name: DeleteUserVocabProgress :exec
+DELETE FROM vocab_progress WHERE user_id = ?
+`
+
+func (q *Queries) DeleteUserVocabProgress(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteUserVocabProgress, userID)
+	return err
+}
+
+const deleteWordStars = `-- name: DeleteWordStars :exec
+DELETE FROM starred_words WHERE language = ? AND word_id = ?
+`
+
+type DeleteWordStarsParams struct {
+	Language string
+	WordID   string
+}
+
+func (q *Queries) DeleteWordStars(ctx context.Context, arg DeleteWordStarsParams) error {
+	_, err := q.db.ExecContext(ctx, deleteWordStars, arg.Language, arg.WordID)
+	return err
+}
+
+const deleteWordVocabProgress = `-- name: DeleteWordVocabProgress :exec
+DELETE FROM vocab_progress WHERE language = ? AND word_id = ?
+`
+
+type DeleteWordVocabProgressParams struct {
+	Language string
+	WordID   string
+}
+
+func (q *Queries) DeleteWordVocabProgress(ctx context.Context, arg DeleteWordVocabProgressParams) error {
+	_, err := q.db.ExecContext(ctx, deleteWordVocabProgress, arg.Language, arg.WordID)
+	return err
+}
+
+const finishPlacementTest = `-- name: FinishPlacementTest :execrows
+UPDATE placement_tests
+SET finished_at = ?
+WHERE id = ? AND finished_at IS NULL
+`
+
+type FinishPlacementTestParams struct {
+	FinishedAt sql.NullTime
+	ID         int64
+}
+
+func (q *Queries) FinishPlacementTest(ctx context.Context, arg FinishPlacementTestParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, finishPlacementTest, arg.FinishedAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const finishQuizSession = `-- name: FinishQuizSession :execrows
+UPDATE quiz_sessions
+SET finished_at = ?, attempt_id = ?
+WHERE id = ? AND finished_at IS NULL
+`
+
+type FinishQuizSessionParams struct {
+	FinishedAt sql.NullTime
+	AttemptID  sql.NullInt64
+	ID         int64
+}
+
+func (q *Queries) FinishQuizSession(ctx context.Context, arg FinishQuizSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, finishQuizSession, arg.FinishedAt, arg.AttemptID, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getLatestLessonRevision = `-- name: GetLatestLessonRevision :one
+SELECT id, language, lesson_id, content, published, author, created_at FROM lesson_revisions
+WHERE language = ? AND lesson_id = ?
+ORDER BY id DESC
+LIMIT 1
+`
+
+type GetLatestLessonRevisionParams struct {
+	Language string
+	LessonID string
+}
+
+func (q *Queries) GetLatestLessonRevision(ctx context.Context, arg GetLatestLessonRevisionParams) (LessonRevision, error) {
+	row := q.db.QueryRowContext(ctx, getLatestLessonRevision, arg.Language, arg.LessonID)
+	var i LessonRevision
+	err := row.Scan(
+		&i.ID,
+		&i.Language,
+		&i.LessonID,
+		&i.Content,
+		&i.Published,
+		&i.Author,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
 const getLessonProgress = `-- name: GetLessonProgress :one
-SELECT id, user_id, language, lesson_id, status, best_score, attempts, last_accessed, completed_at FROM lesson_progress
+SELECT id, user_id, language, lesson_id, status, best_score, attempts, last_accessed, completed_at, first_viewed, last_viewed, placed, status_rank FROM lesson_progress
 WHERE user_id = ? AND language = ? AND lesson_id = ?
 `
 
@@ -121,6 +555,86 @@ func (q *Queries) GetLessonProgress(ctx context.Context, arg GetLessonProgressPa
 		&i.Attempts,
 		&i.LastAccessed,
 		&i.CompletedAt,
+		&i.FirstViewed,
+		&i.LastViewed,
+		&i.Placed,
+		&i.StatusRank,
+	)
+	return i, err
+}
+
+const getLessonRevision = `-- name: GetLessonRevision :one
+SELECT id, language, lesson_id, content, published, author, created_at FROM lesson_revisions WHERE id = ?
+`
+
+func (q *Queries) GetLessonRevision(ctx context.Context, id int64) (LessonRevision, error) {
+	row := q.db.QueryRowContext(ctx, getLessonRevision, id)
+	var i LessonRevision
+	err := row.Scan(
+		&i.ID,
+		&i.Language,
+		&i.LessonID,
+		&i.Content,
+		&i.Published,
+		&i.Author,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getOpenPlacementTest = `-- name: GetOpenPlacementTest :one
+SELECT id, user_id, language, seed, stage, started_at, finished_at FROM placement_tests
+WHERE user_id = ? AND language = ? AND finished_at IS NULL
+ORDER BY id DESC
+LIMIT 1
+`
+
+type GetOpenPlacementTestParams struct {
+	UserID   int64
+	Language string
+}
+
+func (q *Queries) GetOpenPlacementTest(ctx context.Context, arg GetOpenPlacementTestParams) (PlacementTest, error) {
+	row := q.db.QueryRowContext(ctx, getOpenPlacementTest, arg.UserID, arg.Language)
+	var i PlacementTest
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Language,
+		&i.Seed,
+		&i.Stage,
+		&i.StartedAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const getOpenQuizSession = `-- name: GetOpenQuizSession :one
+SELECT id, user_id, language, lesson_id, started_at, finished_at, attempt_id, lesson_version, quiz_seed FROM quiz_sessions
+WHERE user_id = ? AND language = ? AND lesson_id = ? AND finished_at IS NULL
+ORDER BY id DESC
+LIMIT 1
+`
+
+type GetOpenQuizSessionParams struct {
+	UserID   int64
+	Language string
+	LessonID string
+}
+
+func (q *Queries) GetOpenQuizSession(ctx context.Context, arg GetOpenQuizSessionParams) (QuizSession, error) {
+	row := q.db.QueryRowContext(ctx, getOpenQuizSession, arg.UserID, arg.Language, arg.LessonID)
+	var i QuizSession
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Language,
+		&i.LessonID,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.AttemptID,
+		&i.LessonVersion,
+		&i.QuizSeed,
 	)
 	return i, err
 }
@@ -143,7 +657,7 @@ func (q *Queries) GetTotalScore(ctx context.Context, arg GetTotalScoreParams) (i
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, username, email, password_hash, display_name, created_at FROM users WHERE email = ?
+SELECT id, username, email, password_hash, display_name, created_at, timezone, daily_goal, is_admin FROM users WHERE email = ?
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -156,12 +670,15 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.PasswordHash,
 		&i.DisplayName,
 		&i.CreatedAt,
+		&i.Timezone,
+		&i.DailyGoal,
+		&i.IsAdmin,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, username, email, password_hash, display_name, created_at FROM users WHERE id = ?
+SELECT id, username, email, password_hash, display_name, created_at, timezone, daily_goal, is_admin FROM users WHERE id = ?
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
@@ -174,12 +691,15 @@ func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
 		&i.PasswordHash,
 		&i.DisplayName,
 		&i.CreatedAt,
+		&i.Timezone,
+		&i.DailyGoal,
+		&i.IsAdmin,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
-SELECT id, username, email, password_hash, display_name, created_at FROM users WHERE username = ?
+SELECT id, username, email, password_hash, display_name, created_at, timezone, daily_goal, is_admin FROM users WHERE username = ?
 `
 
 func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User, error) {
@@ -192,6 +712,9 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User,
 		&i.PasswordHash,
 		&i.DisplayName,
 		&i.CreatedAt,
+		&i.Timezone,
+		&i.DailyGoal,
+		&i.IsAdmin,
 	)
 	return i, err
 }
@@ -265,36 +788,253 @@ func (q *Queries) GetVocabProgressByWord(ctx context.Context, arg GetVocabProgre
 	return i, err
 }
 
-const listLessonProgress = `-- name: ListLessonProgress :many
-SELECT id, user_id, language, lesson_id, status, best_score, attempts, last_accessed, completed_at FROM lesson_progress
-WHERE user_id = ? AND language = ?
-ORDER BY lesson_id
+const listDailyActivity = `-- name: ListDailyActivity :many
+SELECT user_id, day, xp, quizzes, reviews, seconds, goal FROM daily_activity
+WHERE user_id = ?
+ORDER BY day
 `
 
-type ListLessonProgressParams struct {
-	UserID   int64
-	Language string
-}
-
-func (q *Queries) ListLessonProgress(ctx context.Context, arg ListLessonProgressParams) ([]LessonProgress, error) {
-	rows, err := q.db.QueryContext(ctx, listLessonProgress, arg.UserID, arg.Language)
+func (q *Queries) ListDailyActivity(ctx context.Context, userID int64) ([]DailyActivity, error) {
+	rows, err := q.db.QueryContext(ctx, listDailyActivity, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []LessonProgress
+	var items []DailyActivity
 	for rows.Next() {
-		var i LessonProgress
+		var i DailyActivity
 		if err := rows.Scan(
-			&i.ID,
 			&i.UserID,
-			&i.Language,
-			&i.LessonID,
-			&i.Status,
-			&i.BestScore,
-			&i.Attempts,
+			&i.Day,
+			&i.Xp,
+			&i.Quizzes,
+			&i.Reviews,
+			&i.Seconds,
+			&i.Goal,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLessonDrafts = `-- name: ListLessonDrafts :many
+SELECT r.id, r.language, r.lesson_id, r.content, r.published, r.author, r.created_at
+FROM lesson_revisions r
+WHERE r.published = 0
+  AND r.id = (
+    SELECT MAX(id) FROM lesson_revisions
+    WHERE language = r.language AND lesson_id = r.lesson_id
+  )
+ORDER BY r.language, r.lesson_id
+`
+
+// Lessons whose latest revision is an unpublished draft.
+func (q *Queries) ListLessonDrafts(ctx context.Context) ([]LessonRevision, error) {
+	rows, err := q.db.QueryContext(ctx, listLessonDrafts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LessonRevision
+	for rows.Next() {
+		var i LessonRevision
+		if err := rows.Scan(
+			&i.ID,
+			&i.Language,
+			&i.LessonID,
+			&i.Content,
+			&i.Published,
+			&i.Author,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLessonProgress = `-- name: ListLessonProgress :many
+SELECT id, user_id, language, lesson_id, status, best_score, attempts, last_accessed, completed_at, first_viewed, last_viewed, placed, status_rank FROM lesson_progress
+WHERE user_id = ? AND language = ?
+ORDER BY lesson_id
+`
+
+type ListLessonProgressParams struct {
+	UserID   int64
+	Language string
+}
+
+func (q *Queries) ListLessonProgress(ctx context.Context, arg ListLessonProgressParams) ([]LessonProgress, error) {
+	rows, err := q.db.QueryContext(ctx, listLessonProgress, arg.UserID, arg.Language)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LessonProgress
+	for rows.Next() {
+		var i LessonProgress
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Language,
+			&i.LessonID,
+			&i.Status,
+			&i.BestScore,
+			&i.Attempts,
 			&i.LastAccessed,
 			&i.CompletedAt,
+			&i.FirstViewed,
+			&i.LastViewed,
+			&i.Placed,
+			&i.StatusRank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLessonRevisions = `-- name: ListLessonRevisions :many
+SELECT id, language, lesson_id, content, published, author, created_at FROM lesson_revisions
+WHERE language = ? AND lesson_id = ?
+ORDER BY id DESC
+LIMIT 50
+`
+
+type ListLessonRevisionsParams struct {
+	Language string
+	LessonID string
+}
+
+func (q *Queries) ListLessonRevisions(ctx context.Context, arg ListLessonRevisionsParams) ([]LessonRevision, error) {
+	rows, err := q.db.QueryContext(ctx, listLessonRevisions, arg.Language, arg.LessonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LessonRevision
+	for rows.Next() {
+		var i LessonRevision
+		if err := rows.Scan(
+			&i.ID,
+			&i.Language,
+			&i.LessonID,
+			&i.Content,
+			&i.Published,
+			&i.Author,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listQuestionResponses = `-- name: ListQuestionResponses :many
+SELECT qa.id AS attempt_id, qa.language, qa.lesson_id, qa.lesson_version, qa.score,
+    qr.question_index, qr.credit, qr.correct, qr.answer
+FROM question_results qr
+JOIN quiz_attempts qa ON qa.id = qr.attempt_id
+ORDER BY qa.language, qa.lesson_id, qa.id, qr.question_index
+`
+
+type ListQuestionResponsesRow struct {
+	AttemptID     int64
+	Language      string
+	LessonID      string
+	LessonVersion string
+	Score         int64
+	QuestionIndex int64
+	Credit        float64
+	Correct       bool
+	Answer        string
+}
+
+// Every graded answer across all users with its attempt's total score.
+func (q *Queries) ListQuestionResponses(ctx context.Context) ([]ListQuestionResponsesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listQuestionResponses)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListQuestionResponsesRow
+	for rows.Next() {
+		var i ListQuestionResponsesRow
+		if err := rows.Scan(
+			&i.AttemptID,
+			&i.Language,
+			&i.LessonID,
+			&i.LessonVersion,
+			&i.Score,
+			&i.QuestionIndex,
+			&i.Credit,
+			&i.Correct,
+			&i.Answer,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listQuizAnswers = `-- name: ListQuizAnswers :many
+SELECT id, session_id, question_index, answer, correct, answered_at FROM quiz_answers
+WHERE session_id = ?
+ORDER BY question_index
+`
+
+func (q *Queries) ListQuizAnswers(ctx context.Context, sessionID int64) ([]QuizAnswer, error) {
+	rows, err := q.db.QueryContext(ctx, listQuizAnswers, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []QuizAnswer
+	for rows.Next() {
+		var i QuizAnswer
+		if err := rows.Scan(
+			&i.ID,
+			&i.SessionID,
+			&i.QuestionIndex,
+			&i.Answer,
+			&i.Correct,
+			&i.AnsweredAt,
 		); err != nil {
 			return nil, err
 		}
@@ -310,7 +1050,7 @@ func (q *Queries) ListLessonProgress(ctx context.Context, arg ListLessonProgress
 }
 
 const listQuizAttempts = `-- name: ListQuizAttempts :many
-SELECT id, user_id, language, lesson_id, score, total_questions, correct_answers, attempted_at FROM quiz_attempts
+SELECT id, user_id, language, lesson_id, score, total_questions, correct_answers, attempted_at, lesson_version FROM quiz_attempts
 WHERE user_id = ? AND language = ? AND lesson_id = ?
 ORDER BY attempted_at DESC
 `
@@ -339,6 +1079,7 @@ func (q *Queries) ListQuizAttempts(ctx context.Context, arg ListQuizAttemptsPara
 			&i.TotalQuestions,
 			&i.CorrectAnswers,
 			&i.AttemptedAt,
+			&i.LessonVersion,
 		); err != nil {
 			return nil, err
 		}
@@ -353,17 +1094,544 @@ func (q *Queries) ListQuizAttempts(ctx context.Context, arg ListQuizAttemptsPara
 	return items, nil
 }
 
-const upsertLessonProgress = `-- name: UpsertLessonProgress :one
-INSERT INTO lesson_progress (user_id, language, lesson_id, status, best_score, attempts, last_accessed, completed_at)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+const listSavedWordIDs = `-- name: ListSavedWordIDs :many
+SELECT language, word_id,
+    CAST(SUM(progress) AS INTEGER) AS progress_users,
+    CAST(SUM(starred) AS INTEGER) AS starred_users
+FROM (
+    SELECT language, word_id, 1 AS progress, 0 AS starred FROM vocab_progress
+    UNION ALL
+    SELECT language, word_id, 0 AS progress, 1 AS starred FROM starred_words
+)
+GROUP BY language, word_id
+ORDER BY language, word_id
+`
+
+type ListSavedWordIDsRow struct {
+	Language      string
+	WordID        string
+	ProgressUsers int64
+	StarredUsers  int64
+}
+
+// Every word id that has vocabulary progress or a star, with how many users
+// have each.
+func (q *Queries) ListSavedWordIDs(ctx context.Context) ([]ListSavedWordIDsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSavedWordIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSavedWordIDsRow
+	for rows.Next() {
+		var i ListSavedWordIDsRow
+		if err := rows.Scan(
+			&i.Language,
+			&i.WordID,
+			&i.ProgressUsers,
+			&i.StarredUsers,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStarredWords = `-- name: ListStarredWords :many
+SELECT id, user_id, language, word_id, starred_at FROM starred_words
+WHERE user_id = ? AND language = ?
+ORDER BY starred_at
+`
+
+type ListStarredWordsParams struct {
+	UserID   int64
+	Language string
+}
+
+func (q *Queries) ListStarredWords(ctx context.Context, arg ListStarredWordsParams) ([]StarredWord, error) {
+	rows, err := q.db.QueryContext(ctx, listStarredWords, arg.UserID, arg.Language)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []StarredWord
+	for rows.Next() {
+		var i StarredWord
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Language,
+			&i.WordID,
+			&i.StarredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserAchievements = `-- name: ListUserAchievements :many
+SELECT user_id, achievement_id, earned_at, seen FROM user_achievements
+WHERE user_id = ?
+ORDER BY earned_at, achievement_id
+`
+
+func (q *Queries) ListUserAchievements(ctx context.Context, userID int64) ([]UserAchievement, error) {
+	rows, err := q.db.QueryContext(ctx, listUserAchievements, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserAchievement
+	for rows.Next() {
+		var i UserAchievement
+		if err := rows.Scan(
+			&i.UserID,
+			&i.AchievementID,
+			&i.EarnedAt,
+			&i.Seen,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserSummaries = `-- name: ListUserSummaries :many
+SELECT u.id, u.username, u.email, u.display_name, u.created_at, u.is_admin,
+    (SELECT COUNT(*) FROM lesson_progress lp WHERE lp.user_id = u.id AND lp.status = 'completed') AS lessons_completed,
+    (SELECT COUNT(*) FROM quiz_attempts qa WHERE qa.user_id = u.id) AS quiz_attempts,
+    (SELECT COUNT(*) FROM vocab_progress vp WHERE vp.user_id = u.id) AS words_seen,
+    CAST(COALESCE((SELECT MAX(da.day) FROM daily_activity da WHERE da.user_id = u.id), '') AS TEXT) AS last_active
+FROM users u
+ORDER BY u.created_at DESC, u.id DESC
+`
+
+type ListUserSummariesRow struct {
+	ID               int64
+	Username         string
+	Email            string
+	DisplayName      string
+	CreatedAt        sql.NullTime
+	IsAdmin          bool
+	LessonsCompleted int64
+	QuizAttempts     int64
+	WordsSeen        int64
+	LastActive       string
+}
+
+func (q *Queries) ListUserSummaries(ctx context.Context) ([]ListUserSummariesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUserSummaries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUserSummariesRow
+	for rows.Next() {
+		var i ListUserSummariesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.Email,
+			&i.DisplayName,
+			&i.CreatedAt,
+			&i.IsAdmin,
+			&i.LessonsCompleted,
+			&i.QuizAttempts,
+			&i.WordsSeen,
+			&i.LastActive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markAchievementsSeen = `-- name: MarkAchievementsSeen :exec
+UPDATE user_achievements SET seen = 1
+WHERE user_id = ? AND seen = 0
+`
+
+func (q *Queries) MarkAchievementsSeen(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, markAchievementsSeen, userID)
+	return err
+}
+
+const moveStarredWord = `-- name: MoveStarredWord :exec
+UPDATE OR IGNORE starred_words SET word_id = ?1
+WHERE language = ?2 AND word_id = ?3
+`
+
+type MoveStarredWordParams struct {
+	NewWordID string
+	Language  string
+	OldWordID string
+}
+
+func (q *Queries) MoveStarredWord(ctx context.Context, arg MoveStarredWordParams) error {
+	_, err := q.db.ExecContext(ctx, moveStarredWord, arg.NewWordID, arg.Language, arg.OldWordID)
+	return err
+}
+
+const moveVocabProgress = `-- name: MoveVocabProgress :exec
+UPDATE OR IGNORE vocab_progress SET word_id = ?1
+WHERE language = ?2 AND word_id = ?3
+`
+
+type MoveVocabProgressParams struct {
+	NewWordID string
+	Language  string
+	OldWordID string
+}
+
+// Users who already have progress on the new word id keep it; their rows for
+// the old id are left for DeleteWordVocabProgress.
+func (q *Queries) MoveVocabProgress(ctx context.Context, arg MoveVocabProgressParams) error {
+	_, err := q.db.ExecContext(ctx, moveVocabProgress, arg.NewWordID, arg.Language, arg.OldWordID)
+	return err
+}
+
+const placeLesson = `-- name: PlaceLesson :exec
+INSERT INTO lesson_progress (user_id, language, lesson_id, status, status_rank, completed_at, placed)
+VALUES (?, ?, ?, 'completed', ?, ?, 1)
+ON CONFLICT(user_id, language, lesson_id)
+DO UPDATE SET status = 'completed', status_rank = excluded.status_rank, completed_at = excluded.completed_at, placed = 1
+WHERE lesson_progress.status_rank < excluded.status_rank
+`
+
+type PlaceLessonParams struct {
+	UserID      int64
+	Language    string
+	LessonID    string
+	StatusRank  int64
+	CompletedAt sql.NullTime
+}
+
+// Marks a lesson completed by a placement test. A lesson already completed
+// keeps its earned completion.
+func (q *Queries) PlaceLesson(ctx context.Context, arg PlaceLessonParams) error {
+	_, err := q.db.ExecContext(ctx, placeLesson,
+		arg.UserID,
+		arg.Language,
+		arg.LessonID,
+		arg.StatusRank,
+		arg.CompletedAt,
+	)
+	return err
+}
+
+const recordLessonAttempt = `-- name: RecordLessonAttempt :one
+INSERT INTO lesson_progress (user_id, language, lesson_id, status, status_rank, best_score, attempts, last_accessed, completed_at)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, 1, ?7, ?8)
 ON CONFLICT(user_id, language, lesson_id)
 DO UPDATE SET
-    status = excluded.status,
-    best_score = CASE WHEN excluded.best_score > lesson_progress.best_score THEN excluded.best_score ELSE lesson_progress.best_score END,
-    attempts = excluded.attempts,
+    status = CASE WHEN excluded.status_rank > lesson_progress.status_rank THEN excluded.status ELSE lesson_progress.status END,
+    status_rank = MAX(excluded.status_rank, lesson_progress.status_rank),
+    best_score = CASE WHEN excluded.best_score > COALESCE(lesson_progress.best_score, 0) THEN excluded.best_score ELSE lesson_progress.best_score END,
+    attempts = COALESCE(lesson_progress.attempts, 0) + 1,
     last_accessed = excluded.last_accessed,
-    completed_at = COALESCE(excluded.completed_at, lesson_progress.completed_at)
-RETURNING id, user_id, language, lesson_id, status, best_score, attempts, last_accessed, completed_at
+    completed_at = COALESCE(lesson_progress.completed_at, excluded.completed_at),
+    placed = CASE WHEN CAST(?9 AS BOOLEAN) THEN 0 ELSE lesson_progress.placed END
+RETURNING id, user_id, language, lesson_id, status, best_score, attempts, last_accessed, completed_at, first_viewed, last_viewed, placed, status_rank
+`
+
+type RecordLessonAttemptParams struct {
+	UserID       int64
+	Language     string
+	LessonID     string
+	Status       string
+	StatusRank   int64
+	BestScore    sql.NullInt64
+	LastAccessed sql.NullTime
+	CompletedAt  sql.NullTime
+	Passed       bool
+}
+
+// Records a quiz attempt. Passing one turns a lesson completed by a
+// placement test into an earned completion; failing it leaves it placed.
+func (q *Queries) RecordLessonAttempt(ctx context.Context, arg RecordLessonAttemptParams) (LessonProgress, error) {
+	row := q.db.QueryRowContext(ctx, recordLessonAttempt,
+		arg.UserID,
+		arg.Language,
+		arg.LessonID,
+		arg.Status,
+		arg.StatusRank,
+		arg.BestScore,
+		arg.LastAccessed,
+		arg.CompletedAt,
+		arg.Passed,
+	)
+	var i LessonProgress
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Language,
+		&i.LessonID,
+		&i.Status,
+		&i.BestScore,
+		&i.Attempts,
+		&i.LastAccessed,
+		&i.CompletedAt,
+		&i.FirstViewed,
+		&i.LastViewed,
+		&i.Placed,
+		&i.StatusRank,
+	)
+	return i, err
+}
+
+const recordLessonView = `-- name: RecordLessonView :one
+INSERT INTO lesson_progress (user_id, language, lesson_id, status, status_rank, last_accessed, first_viewed, last_viewed)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?6, ?6)
+ON CONFLICT(user_id, language, lesson_id)
+DO UPDATE SET
+    status = CASE WHEN excluded.status_rank > lesson_progress.status_rank THEN excluded.status ELSE lesson_progress.status END,
+    status_rank = MAX(excluded.status_rank, lesson_progress.status_rank),
+    last_accessed = excluded.last_accessed,
+    first_viewed = COALESCE(lesson_progress.first_viewed, excluded.first_viewed),
+    last_viewed = excluded.last_viewed
+RETURNING id, user_id, language, lesson_id, status, best_score, attempts, last_accessed, completed_at, first_viewed, last_viewed, placed, status_rank
+`
+
+type RecordLessonViewParams struct {
+	UserID     int64
+	Language   string
+	LessonID   string
+	Status     string
+	StatusRank int64
+	ViewedAt   sql.NullTime
+}
+
+func (q *Queries) RecordLessonView(ctx context.Context, arg RecordLessonViewParams) (LessonProgress, error) {
+	row := q.db.QueryRowContext(ctx, recordLessonView,
+		arg.UserID,
+		arg.Language,
+		arg.LessonID,
+		arg.Status,
+		arg.StatusRank,
+		arg.ViewedAt,
+	)
+	var i LessonProgress
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Language,
+		&i.LessonID,
+		&i.Status,
+		&i.BestScore,
+		&i.Attempts,
+		&i.LastAccessed,
+		&i.CompletedAt,
+		&i.FirstViewed,
+		&i.LastViewed,
+		&i.Placed,
+		&i.StatusRank,
+	)
+	return i, err
+}
+
+const saveQuestionResult = `-- name: SaveQuestionResult :exec
+INSERT INTO question_results (attempt_id, question_index, credit, correct, answer)
+VALUES (?, ?, ?, ?, ?)
+`
+
+type SaveQuestionResultParams struct {
+	AttemptID     int64
+	QuestionIndex int64
+	Credit        float64
+	Correct       bool
+	Answer        string
+}
+
+func (q *Queries) SaveQuestionResult(ctx context.Context, arg SaveQuestionResultParams) error {
+	_, err := q.db.ExecContext(ctx, saveQuestionResult,
+		arg.AttemptID,
+		arg.QuestionIndex,
+		arg.Credit,
+		arg.Correct,
+		arg.Answer,
+	)
+	return err
+}
+
+const saveQuizAnswer = `-- name: SaveQuizAnswer :exec
+INSERT INTO quiz_answers (session_id, question_index, answer, correct)
+VALUES (?, ?, ?, ?)
+ON CONFLICT(session_id, question_index) DO NOTHING
+`
+
+type SaveQuizAnswerParams struct {
+	SessionID     int64
+	QuestionIndex int64
+	Answer        string
+	Correct       bool
+}
+
+// The first answer to a question stands; resubmitting it is a no-op.
+func (q *Queries) SaveQuizAnswer(ctx context.Context, arg SaveQuizAnswerParams) error {
+	_, err := q.db.ExecContext(ctx, saveQuizAnswer,
+		arg.SessionID,
+		arg.QuestionIndex,
+		arg.Answer,
+		arg.Correct,
+	)
+	return err
+}
+
+const setUserAdmin = `-- name: SetUserAdmin :exec
+UPDATE users SET is_admin = ? WHERE id = ?
+`
+
+type SetUserAdminParams struct {
+	IsAdmin bool
+	ID      int64
+}
+
+func (q *Queries) SetUserAdmin(ctx context.Context, arg SetUserAdminParams) error {
+	_, err := q.db.ExecContext(ctx, setUserAdmin, arg.IsAdmin, arg.ID)
+	return err
+}
+
+const starWord = `-- name: StarWord :exec
+INSERT INTO starred_words (user_id, language, word_id)
+VALUES (?, ?, ?)
+ON CONFLICT(user_id, language, word_id) DO NOTHING
+`
+
+type StarWordParams struct {
+	UserID   int64
+	Language string
+	WordID   string
+}
+
+func (q *Queries) StarWord(ctx context.Context, arg StarWordParams) error {
+	_, err := q.db.ExecContext(ctx, starWord, arg.UserID, arg.Language, arg.WordID)
+	return err
+}
+
+const unlockLesson = `-- name: UnlockLesson :exec
+INSERT INTO lesson_progress (user_id, language, lesson_id, status, status_rank)
+VALUES (?, ?, ?, 'available', ?)
+ON CONFLICT(user_id, language, lesson_id)
+DO UPDATE SET status = 'available', status_rank = excluded.status_rank
+WHERE lesson_progress.status_rank < excluded.status_rank
+`
+
+type UnlockLessonParams struct {
+	UserID     int64
+	Language   string
+	LessonID   string
+	StatusRank int64
+}
+
+func (q *Queries) UnlockLesson(ctx context.Context, arg UnlockLessonParams) error {
+	_, err := q.db.ExecContext(ctx, unlockLesson,
+		arg.UserID,
+		arg.Language,
+		arg.LessonID,
+		arg.StatusRank,
+	)
+	return err
+}
+
+const unstarWord = `-- name: UnstarWord :exec
+DELETE FROM starred_words
+WHERE user_id = ? AND language = ? AND word_id = ?
+`
+
+type UnstarWordParams struct {
+	UserID   int64
+	Language string
+	WordID   string
+}
+
+func (q *Queries) UnstarWord(ctx context.Context, arg UnstarWordParams) error {
+	_, err := q.db.ExecContext(ctx, unstarWord, arg.UserID, arg.Language, arg.WordID)
+	return err
+}
+
+const updateUserGoal = `-- name: UpdateUserGoal :exec
+UPDATE users SET daily_goal = ? WHERE id = ?
+`
+
+type UpdateUserGoalParams struct {
+	DailyGoal int64
+	ID        int64
+}
+
+func (q *Queries) UpdateUserGoal(ctx context.Context, arg UpdateUserGoalParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserGoal, arg.DailyGoal, arg.ID)
+	return err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users SET password_hash = ? WHERE id = ?
+`
+
+type UpdateUserPasswordParams struct {
+	PasswordHash string
+	ID           int64
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.PasswordHash, arg.ID)
+	return err
+}
+
+const updateUserTimezone = `-- name: UpdateUserTimezone :exec
+UPDATE users SET timezone = ? WHERE id = ?
+`
+
+type UpdateUserTimezoneParams struct {
+	Timezone string
+	ID       int64
+}
+
+func (q *Queries) UpdateUserTimezone(ctx context.Context, arg UpdateUserTimezoneParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserTimezone, arg.Timezone, arg.ID)
+	return err
+}
+
+const upsertLessonProgress = `-- name: UpsertLessonProgress :one
+INSERT INTO lesson_progress (user_id, language, lesson_id, status, status_rank, best_score, attempts, last_accessed, completed_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(user_id, language, lesson_id)
+DO UPDATE SET
+    status = CASE WHEN excluded.status_rank > lesson_progress.status_rank THEN excluded.status ELSE lesson_progress.status END,
+    status_rank = MAX(excluded.status_rank, lesson_progress.status_rank),
+    best_score = CASE WHEN excluded.best_score > COALESCE(lesson_progress.best_score, 0) THEN excluded.best_score ELSE lesson_progress.best_score END,
+    attempts = COALESCE(excluded.attempts, lesson_progress.attempts),
+    last_accessed = COALESCE(excluded.last_accessed, lesson_progress.last_accessed),
+    completed_at = COALESCE(lesson_progress.completed_at, excluded.completed_at)
+RETURNING id, user_id, language, lesson_id, status, best_score, attempts, last_accessed, completed_at, first_viewed, last_viewed, placed, status_rank
 `
 
 type UpsertLessonProgressParams struct {
@@ -371,18 +1639,23 @@ type UpsertLessonProgressParams struct {
 	Language     string
 	LessonID     string
 	Status       string
+	StatusRank   int64
 	BestScore    sql.NullInt64
 	Attempts     sql.NullInt64
 	LastAccessed sql.NullTime
 	CompletedAt  sql.NullTime
 }
 
+// Status only moves forward (locked → available → in_progress → completed),
+// by the status_rank the caller passes from lessons.Status.Rank; NULL values
+// leave the existing columns untouched.
 func (q *Queries) UpsertLessonProgress(ctx context.Context, arg UpsertLessonProgressParams) (LessonProgress, error) {
 	row := q.db.QueryRowContext(ctx, upsertLessonProgress,
 		arg.UserID,
 		arg.Language,
 		arg.LessonID,
 		arg.Status,
+		arg.StatusRank,
 		arg.BestScore,
 		arg.Attempts,
 		arg.LastAccessed,
@@ -399,6 +1672,10 @@ func (q *Queries) UpsertLessonProgress(ctx context.Context, arg UpsertLessonProg
 		&i.Attempts,
 		&i.LastAccessed,
 		&i.CompletedAt,
+		&i.FirstViewed,
+		&i.LastViewed,
+		&i.Placed,
+		&i.StatusRank,
 	)
 	return i, err
 }
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"speakeasy/internal/achievements"
+	"speakeasy/internal/db"
+	"speakeasy/internal/lessons"
+)
+
+// BadgeView is a badge as shown on the dashboard: earned or still locked,
+// with progress towards it.
+type BadgeView struct {
+	achievements.Badge
+	Earned   bool
+	EarnedAt time.Time
+	Progress int
+	New      bool // not yet shown on the dashboard, or awarded by the quiz shown
+}
+
+// awardAchievements evaluates every badge against the user's current stats
+// and stores the ones not yet earned. It returns only the newly awarded
+// badges. Badges are never taken away, so it is safe to call after any
+// progress event; badges added later are awarded the next time it runs.
+func awardAchievements(ctx context.Context, q *db.Queries, userID int64) ([]achievements.Badge, error) {
+	stats, err := achievementStats(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var awarded []achievements.Badge
+	for _, b := range achievements.Eligible(stats) {
+		n, err := q.AwardAchievement(ctx, db.AwardAchievementParams{
+			UserID:        userID,
+			AchievementID: b.ID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("award %q: %w", b.ID, err)
+		}
+		if n > 0 {
+			awarded = append(awarded, b)
+		}
+	}
+	return awarded, nil
+}
+
+// achievementStats gathers the value of every achievements.Metric for a user.
+func achievementStats(ctx context.Context, q *db.Queries, userID int64) (achievements.Stats, error) {
+	stats := make(achievements.Stats)
+
+	for _, lang := range lessons.GetLanguages() {
+		// Lessons skipped by a placement test earn nothing
+		completed, err := q.CountEarnedLessons(ctx, db.CountEarnedLessonsParams{
+			UserID:   userID,
+			Language: lang.Slug,
+		})
+		if err != nil {
+			return nil, err
+		}
+		stats[achievements.LessonsCompleted] += int(completed)
+		if total := len(lessons.GetLessons(lang.Slug, false)); total > 0 && int(completed) >= total {
+			stats[achievements.LanguagesCompleted]++
+		}
+	}
+
+	perfect, err := q.CountPerfectQuizzes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	stats[achievements.PerfectQuizzes] = int(perfect)
+
+	mastered, err := q.CountMasteredWords(ctx, db.CountMasteredWordsParams{
+		UserID:       userID,
+		MasteryLevel: sql.NullInt64{Int64: masteredLevel, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats[achievements.WordsMastered] = int(mastered)
+
+	user, err := q.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	streak, _, err := userActivity(ctx, q, &user)
+	if err != nil {
+		return nil, err
+	}
+	stats[achievements.LongestStreak] = streak.Longest
+
+	return stats, nil
+}
+
+// badgeViews lists every badge for the dashboard, earned ones first in the
+// order they were earned, then locked ones in declaration order. Earned
+// badges the dashboard hasn't shown yet are marked New.
+func badgeViews(ctx context.Context, q *db.Queries, userID int64) ([]BadgeView, error) {
+	rows, err := q.ListUserAchievements(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := achievementStats(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var views []BadgeView
+	earned := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		b, ok := achievements.Get(row.AchievementID)
+		if !ok {
+			// A badge that has since been removed from the rules
+			continue
+		}
+		earned[b.ID] = true
+		views = append(views, BadgeView{
+			Badge:    b,
+			Earned:   true,
+			EarnedAt: row.EarnedAt.Time,
+			Progress: 100,
+			New:      !row.Seen,
+		})
+	}
+	for _, b := range achievements.All() {
+		if !earned[b.ID] {
+			views = append(views, BadgeView{Badge: b, Progress: b.Progress(stats)})
+		}
+	}
+	return views, nil
+}
+
+// newBadgeViews presents badges that were just awarded.
+func newBadgeViews(awarded []achievements.Badge) []BadgeView {
+	views := make([]BadgeView, len(awarded))
+	for i, b := range awarded {
+		views[i] = BadgeView{Badge: b, Earned: true, EarnedAt: time.Now(), Progress: 100, New: true}
+	}
+	return views
+}
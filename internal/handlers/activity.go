@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"speakeasy/internal/activity"
+	"speakeasy/internal/db"
+	"speakeasy/internal/middleware"
+)
+
+// heartbeatSeconds is how often lesson pages report reading time; longer
+// claims are capped so a stale tab can't earn more than one interval.
+const heartbeatSeconds = 60
+
+// readingIdle is how long after its last heartbeat a reader's clock is
+// dropped. Past one interval the clock no longer limits what is credited and
+// only holds a few seconds of carry; past two the reader has most likely
+// left the page.
+const readingIdle = 2 * heartbeatSeconds * time.Second
+
+// heatmapWeeks is how much history the dashboard calendar shows.
+const heatmapWeeks = 26
+
+type ActivityHandler struct {
+	queries *db.Queries
+
+	mu      sync.Mutex
+	reading map[int64]*readingClock // by user ID
+	swept   time.Time               // when idle clocks were last dropped
+}
+
+// readingClock is when a user's reading time was last credited, and the
+// seconds credited since too few to have earned their XP yet.
+type readingClock struct {
+	last  time.Time
+	carry int64
+}
+
+func NewActivityHandler(q *db.Queries) *ActivityHandler {
+	return &ActivityHandler{queries: q, reading: make(map[int64]*readingClock)}
+}
+
+// Heartbeat records time spent reading a lesson page. The page posts every
+// heartbeatSeconds while it is visible.
+func (h *ActivityHandler) Heartbeat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := middleware.GetUserID(r.Context())
+
+	seconds, err := strconv.ParseInt(r.FormValue("seconds"), 10, 64)
+	if err != nil || seconds <= 0 {
+		http.Error(w, "invalid seconds", http.StatusBadRequest)
+		return
+	}
+	seconds, xp := h.creditReading(userID, seconds, time.Now())
+	if seconds == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err := recordActivity(r.Context(), h.queries, userID, db.AddDailyActivityParams{
+		Seconds: seconds,
+		Xp:      xp,
+	}); err != nil {
+		http.Error(w, "could not record activity", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// creditReading decides how much of a heartbeat's claimed reading time to
+// credit: no more than the time since the user's last heartbeat, however many
+// requests or tabs send them, and at most heartbeatSeconds. Seconds left over
+// from turning time into XP carry over to the next heartbeat.
+func (h *ActivityHandler) creditReading(userID, claimed int64, now time.Time) (seconds, xp int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Drop the clocks of users who stopped reading, at most once per idle
+	// period, so the map only holds current readers
+	if now.Sub(h.swept) >= readingIdle {
+		for id, c := range h.reading {
+			if now.Sub(c.last) >= readingIdle {
+				delete(h.reading, id)
+			}
+		}
+		h.swept = now
+	}
+
+	seconds = min(claimed, heartbeatSeconds)
+	clock, ok := h.reading[userID]
+	if !ok {
+		clock = &readingClock{}
+		h.reading[userID] = clock
+	} else {
+		seconds = min(seconds, int64(now.Sub(clock.last).Round(time.Second)/time.Second))
+	}
+	if seconds <= 0 {
+		return 0, 0
+	}
+	clock.last = now
+
+	total := clock.carry + seconds
+	xp = total * activity.XPPerMinute / 60
+	clock.carry = total - xp*60/activity.XPPerMinute
+	return seconds, xp
+}
+
+// SetGoal changes the user's daily XP goal from the dashboard form.
+func (h *ActivityHandler) SetGoal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := middleware.GetUserID(r.Context())
+
+	goal, err := strconv.ParseInt(r.FormValue("goal"), 10, 64)
+	if err != nil || !activity.ValidGoal(goal) {
+		http.Error(w, "invalid goal", http.StatusBadRequest)
+		return
+	}
+	if err := h.queries.UpdateUserGoal(r.Context(), db.UpdateUserGoalParams{
+		DailyGoal: goal,
+		ID:        userID,
+	}); err != nil {
+		http.Error(w, "could not save goal", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// SetTimezone stores the browser's IANA timezone so days roll over at the
+// user's midnight. The dashboard sends it whenever it differs from the saved one.
+func (h *ActivityHandler) SetTimezone(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := middleware.GetUserID(r.Context())
+
+	tz := r.FormValue("timezone")
+	if _, err := time.LoadLocation(tz); err != nil || tz == "" || tz == "Local" {
+		http.Error(w, "invalid timezone", http.StatusBadRequest)
+		return
+	}
+	if err := h.queries.UpdateUserTimezone(r.Context(), db.UpdateUserTimezoneParams{
+		Timezone: tz,
+		ID:       userID,
+	}); err != nil {
+		http.Error(w, "could not save timezone", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// recordActivity adds delta to today's activity row, using the user's
+// timezone for "today" and snapshotting their current goal.
+func recordActivity(ctx context.Context, q *db.Queries, userID int64, delta db.AddDailyActivityParams) error {
+	user, err := q.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	delta.UserID = userID
+	delta.Day = activity.Today(activity.Location(user.Timezone))
+	delta.Goal = user.DailyGoal
+	return q.AddDailyActivity(ctx, delta)
+}
+
+// userActivity loads a user's history and computes their streak and heatmap.
+func userActivity(ctx context.Context, q *db.Queries, user *db.User) (activity.Streak, [][]activity.HeatmapCell, error) {
+	rows, err := q.ListDailyActivity(ctx, user.ID)
+	if err != nil {
+		return activity.Streak{}, nil, err
+	}
+	days := make([]activity.Day, len(rows))
+	for i, row := range rows {
+		days[i] = activity.Day{Date: row.Day, XP: row.Xp, Goal: row.Goal}
+	}
+
+	today := activity.Today(activity.Location(user.Timezone))
+	return activity.ComputeStreak(days, today, user.DailyGoal),
+		activity.Heatmap(days, today, heatmapWeeks),
+		nil
+}
@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"speakeasy/internal/db"
+	"speakeasy/internal/itemstats"
+	"speakeasy/internal/lessons"
+	"speakeasy/internal/middleware"
+	"speakeasy/internal/tts"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// tempPasswordLength is the length of passwords generated by an admin reset.
+// The alphabet leaves out characters that are easy to misread.
+const (
+	tempPasswordLength   = 12
+	tempPasswordAlphabet = "abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+// adminNotices are the confirmations shown after a redirect back to /admin.
+var adminNotices = map[string]string{
+	"reset":   "Progress reset.",
+	"deleted": "Account deleted.",
+	"granted": "Admin role granted.",
+	"revoked": "Admin role revoked.",
+}
+
+type AdminHandler struct {
+	database *sql.DB
+	queries  *db.Queries
+	sessions *middleware.SessionStore
+	tts      *tts.Client
+	tmpl     *TemplateRenderer
+}
+
+func NewAdminHandler(database *sql.DB, q *db.Queries, s *middleware.SessionStore, c *tts.Client, t *TemplateRenderer) *AdminHandler {
+	return &AdminHandler{database: database, queries: q, sessions: s, tts: c, tmpl: t}
+}
+
+// IsAdmin looks up the admin role for middleware.RequireAdmin.
+func (h *AdminHandler) IsAdmin(ctx context.Context, userID int64) (bool, error) {
+	user, err := h.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.IsAdmin, nil
+}
+
+// Users renders the admin dashboard: every account with a progress summary,
+// and the TTS cache.
+func (h *AdminHandler) Users(w http.ResponseWriter, r *http.Request) {
+	h.renderUsers(w, r, adminNotices[r.URL.Query().Get("notice")], "", "")
+}
+
+func (h *AdminHandler) renderUsers(w http.ResponseWriter, r *http.Request, notice, tempPasswordFor, tempPassword string) {
+	userID := middleware.GetUserID(r.Context())
+	users, err := h.queries.ListUserSummaries(r.Context())
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't load the user list.")
+		return
+	}
+
+	st := h.tts.Stats()
+	hitRate := 0
+	if st.Requests > 0 {
+		hitRate = int(st.Hits * 100 / st.Requests)
+	}
+
+	h.tmpl.Render(w, "admin.html", map[string]interface{}{
+		"Title":           "Admin",
+		"User":            getUser(r.Context(), h.queries, userID),
+		"Users":           users,
+		"CurrentUserID":   userID,
+		"Notice":          notice,
+		"TempPasswordFor": tempPasswordFor,
+		"TempPassword":    tempPassword,
+		"TTS":             st,
+		"TTSHitRate":      hitRate,
+		"TTSCacheSize":    formatBytes(st.CacheBytes),
+		"TTSOverrideSize": formatBytes(st.OverrideBytes),
+	})
+}
+
+// UserAction handles POST /admin/users/{id}/{action}, where action is
+// reset-progress, reset-password, delete, grant-admin or revoke-admin.
+// Admins can't delete themselves or revoke their own role, so there is
+// always someone left who can undo a mistake.
+func (h *AdminHandler) UserAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	// parts[0] = "admin", parts[1] = "users", parts[2] = id, parts[3] = action
+	if len(parts) != 4 {
+		http.NotFound(w, r)
+		return
+	}
+	targetID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	target, err := h.queries.GetUserByID(r.Context(), targetID)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	self := targetID == middleware.GetUserID(r.Context())
+
+	switch parts[3] {
+	case "reset-progress":
+		if err := h.inTx(r.Context(), func(q *db.Queries) error {
+			return clearProgress(r.Context(), q, targetID)
+		}); err != nil {
+			serverError(w, r, h.tmpl, err, "We couldn't reset that user's progress.")
+			return
+		}
+		http.Redirect(w, r, "/admin?notice=reset", http.StatusSeeOther)
+
+	case "reset-password":
+		password, err := tempPassword()
+		if err != nil {
+			serverError(w, r, h.tmpl, err, "We couldn't generate a password.")
+			return
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			serverError(w, r, h.tmpl, err, "We couldn't generate a password.")
+			return
+		}
+		if err := h.queries.UpdateUserPassword(r.Context(), db.UpdateUserPasswordParams{
+			PasswordHash: string(hash),
+			ID:           targetID,
+		}); err != nil {
+			serverError(w, r, h.tmpl, err, "We couldn't reset that user's password.")
+			return
+		}
+		if !self {
+			h.sessions.DeleteUser(targetID)
+		}
+		// Rendered rather than redirected so the password never appears in a URL
+		h.renderUsers(w, r, "", target.Username, password)
+
+	case "delete":
+		if self {
+			http.Error(w, "you can't delete your own account here", http.StatusBadRequest)
+			return
+		}
+		if err := h.inTx(r.Context(), func(q *db.Queries) error {
+			if err := clearProgress(r.Context(), q, targetID); err != nil {
+				return err
+			}
+			if err := q.DeleteUserStarredWords(r.Context(), targetID); err != nil {
+				return fmt.Errorf("delete starred words: %w", err)
+			}
+			return q.DeleteUser(r.Context(), targetID)
+		}); err != nil {
+			serverError(w, r, h.tmpl, err, "We couldn't delete that account.")
+			return
+		}
+		h.sessions.DeleteUser(targetID)
+		http.Redirect(w, r, "/admin?notice=deleted", http.StatusSeeOther)
+
+	case "grant-admin", "revoke-admin":
+		grant := parts[3] == "grant-admin"
+		if self && !grant {
+			http.Error(w, "you can't revoke your own admin role", http.StatusBadRequest)
+			return
+		}
+		if err := h.queries.SetUserAdmin(r.Context(), db.SetUserAdminParams{
+			IsAdmin: grant,
+			ID:      targetID,
+		}); err != nil {
+			serverError(w, r, h.tmpl, err, "We couldn't change that user's role.")
+			return
+		}
+		notice := "revoked"
+		if grant {
+			notice = "granted"
+		}
+		http.Redirect(w, r, "/admin?notice="+notice, http.StatusSeeOther)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Questions renders the item analysis report: per question, the share of
+// graded answers that were correct, how well it discriminates, and which
+// options multiple-choice answers picked. Hardest first unless ?sort=lesson;
+// ?flagged=1 keeps only questions with flags.
+func (h *AdminHandler) Questions(w http.ResponseWriter, r *http.Request) {
+	responses, err := itemstats.Load(r.Context(), h.queries)
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't load question statistics.")
+		return
+	}
+
+	query := r.URL.Query()
+	langFilter := query.Get("lang")
+	flagged := query.Get("flagged") == "1"
+	sortBy := query.Get("sort")
+	if sortBy != "lesson" {
+		sortBy = "hardest"
+	}
+
+	var items []itemstats.Item
+	for _, it := range itemstats.Analyze(responses) {
+		if langFilter != "" && it.Language != langFilter {
+			continue
+		}
+		if flagged && len(it.Flags) == 0 {
+			continue
+		}
+		items = append(items, it)
+	}
+	if sortBy == "hardest" {
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].PercentCorrect < items[j].PercentCorrect
+		})
+	}
+
+	names := make(map[string]string)
+	for _, l := range lessons.GetLanguages() {
+		names[l.Slug] = l.DisplayName
+	}
+
+	h.tmpl.Render(w, "admin_questions.html", map[string]interface{}{
+		"Title":         "Question Analysis",
+		"User":          getUser(r.Context(), h.queries, middleware.GetUserID(r.Context())),
+		"Items":         items,
+		"Languages":     lessons.GetLanguages(),
+		"LanguageNames": names,
+		"LangFilter":    langFilter,
+		"Flagged":       flagged,
+		"Sort":          sortBy,
+		"MinResponses":  itemstats.MinResponses,
+	})
+}
+
+// QuestionsCSV downloads the item analysis for every question as CSV, the
+// same report cmd/itemstats writes.
+func (h *AdminHandler) QuestionsCSV(w http.ResponseWriter, r *http.Request) {
+	responses, err := itemstats.Load(r.Context(), h.queries)
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't load question statistics.")
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="speakeasy-questions.csv"`)
+	if err := itemstats.WriteCSV(w, itemstats.Analyze(responses)); err != nil {
+		slog.Error("write question csv", "error", err)
+	}
+}
+
+// inTx runs fn with queries bound to a transaction, committing if it succeeds.
+func (h *AdminHandler) inTx(ctx context.Context, fn func(q *db.Queries) error) error {
+	tx, err := h.database.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(h.queries.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// clearProgress deletes everything a user has learned or earned, leaving the
+// account, its settings and starred words. Children are deleted before the
+// rows they reference.
+func clearProgress(ctx context.Context, q *db.Queries, userID int64) error {
+	steps := []struct {
+		what string
+		fn   func(context.Context, int64) error
+	}{
+		{"question results", q.DeleteUserQuestionResults},
+		{"quiz answers", q.DeleteUserQuizAnswers},
+		{"quiz sessions", q.DeleteUserQuizSessions},
+		{"placement tests", q.DeleteUserPlacementTests},
+		{"quiz attempts", q.DeleteUserQuizAttempts},
+		{"lesson progress", q.DeleteUserLessonProgress},
+		{"vocab progress", q.DeleteUserVocabProgress},
+		{"daily activity", q.DeleteUserDailyActivity},
+		{"achievements", q.DeleteUserAchievements},
+	}
+	for _, s := range steps {
+		if err := s.fn(ctx, userID); err != nil {
+			return fmt.Errorf("delete %s: %w", s.what, err)
+		}
+	}
+	return nil
+}
+
+// tempPassword returns a random password for an admin reset.
+func tempPassword() (string, error) {
+	b := make([]byte, tempPasswordLength)
+	max := big.NewInt(int64(len(tempPasswordAlphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = tempPasswordAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// formatBytes formats a size as B, KB or MB.
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return strconv.FormatFloat(float64(n)/(1<<20), 'f', 1, 64) + " MB"
+	case n >= 1<<10:
+		return strconv.FormatFloat(float64(n)/(1<<10), 'f', 1, 64) + " KB"
+	}
+	return strconv.FormatInt(n, 10) + " B"
+}
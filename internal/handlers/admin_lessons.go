@@ -0,0 +1,869 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"speakeasy/internal/db"
+	"speakeasy/internal/lessons"
+	"speakeasy/internal/middleware"
+	"speakeasy/internal/orphans"
+)
+
+// The lesson editor edits one lesson at a time through a form whose field
+// names are JSON paths relative to the lesson ("sections[0].items[2].english"),
+// the same paths lessons.FieldError reports, so validation errors are shown
+// next to the field they are about. Adding, removing and moving list entries
+// round-trips the whole form through the server; nothing is saved until the
+// Save button is pressed.
+//
+// Saving writes the lesson file back to the language's lessons.Source and
+// reloads the language. Languages without one (built-in lessons outside
+// development mode, zipped language packs) can still be edited: the lesson
+// is stored as an unpublished draft revision, to be downloaded and committed.
+
+var lessonIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// lessonEditorNotices are the confirmations shown after saving.
+var lessonEditorNotices = map[string]string{
+	"saved": "Saved and published.",
+	"draft": "Saved as a draft. This language's lesson files are read-only here, so download the JSON below and commit it.",
+}
+
+// orphanNotices are the confirmations shown on the lesson index after fixing
+// saved vocabulary for a word no lesson defines, and orphanErrors the
+// problems.
+var (
+	orphanNotices = map[string]string{
+		"moved":   "Saved vocabulary moved to the new word.",
+		"deleted": "Saved vocabulary deleted.",
+	}
+	orphanErrors = map[string]string{
+		"unknown-word": "No lesson of that language has the word you tried to move to.",
+	}
+)
+
+// adminLanguageLessons is one language on the lesson editor index.
+type adminLanguageLessons struct {
+	Language  lessons.Language
+	Dir       string // where saves are written; empty if read-only
+	Lessons   []adminLessonRow
+	NewDrafts []db.LessonRevision // drafts of lessons that aren't registered yet
+
+	// Orphans is the saved vocabulary for word IDs no lesson defines, to be
+	// moved to one of WordIDs or deleted.
+	Orphans []orphans.Word
+	WordIDs []string
+}
+
+type adminLessonRow struct {
+	ID          string
+	Title       string
+	Order       int
+	Draft       bool // hidden from learners
+	Checkpoint  bool
+	Unpublished bool // the latest revision is an unpublished draft
+}
+
+// Lessons lists every language's lessons for editing.
+func (h *AdminHandler) Lessons(w http.ResponseWriter, r *http.Request) {
+	drafts, err := h.queries.ListLessonDrafts(r.Context())
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't load lesson drafts.")
+		return
+	}
+	byLesson := make(map[string]db.LessonRevision, len(drafts))
+	for _, d := range drafts {
+		byLesson[d.Language+"/"+d.LessonID] = d
+	}
+	orphaned, err := orphans.Find(r.Context(), h.queries)
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't check saved vocabulary.")
+		return
+	}
+
+	var languages []adminLanguageLessons
+	for _, lang := range lessons.GetLanguages() {
+		entry := adminLanguageLessons{Language: lang}
+		if src, ok := lessons.GetSource(lang.Slug); ok {
+			entry.Dir = src.Dir
+		}
+		for _, l := range lessons.GetAllLessons(lang.Slug) {
+			_, unpublished := byLesson[lang.Slug+"/"+l.ID]
+			entry.Lessons = append(entry.Lessons, adminLessonRow{ID: l.ID, Title: l.Title, Order: l.Order, Draft: l.Draft, Checkpoint: l.Checkpoint != nil, Unpublished: unpublished})
+		}
+		for _, d := range drafts {
+			if d.Language == lang.Slug && lessons.GetLesson(lang.Slug, d.LessonID) == nil {
+				entry.NewDrafts = append(entry.NewDrafts, d)
+			}
+		}
+		for _, o := range orphaned {
+			if o.Language == lang.Slug {
+				entry.Orphans = append(entry.Orphans, o)
+			}
+		}
+		if len(entry.Orphans) > 0 {
+			for id := range orphans.WordIDs(lang.Slug) {
+				entry.WordIDs = append(entry.WordIDs, id)
+			}
+			sort.Strings(entry.WordIDs)
+		}
+		languages = append(languages, entry)
+	}
+
+	h.tmpl.Render(w, "admin_lessons.html", map[string]interface{}{
+		"Title":     "Lessons",
+		"User":      getUser(r.Context(), h.queries, middleware.GetUserID(r.Context())),
+		"Notice":    orphanNotices[r.URL.Query().Get("notice")],
+		"Error":     orphanErrors[r.URL.Query().Get("notice")],
+		"Languages": languages,
+	})
+}
+
+// OrphanAction handles POST /admin/lessons/orphans, which moves the saved
+// vocabulary for a word no lesson defines to another word (action "move",
+// with new_word_id) or deletes it (action "delete").
+func (h *AdminHandler) OrphanAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	language, wordID := r.FormValue("language"), r.FormValue("word_id")
+	if lessons.GetLanguage(language) == nil || wordID == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var notice string
+	var err error
+	switch r.FormValue("action") {
+	case "move":
+		newID := strings.TrimSpace(r.FormValue("new_word_id"))
+		if !orphans.WordIDs(language)[newID] {
+			http.Redirect(w, r, "/admin/lessons?notice=unknown-word", http.StatusSeeOther)
+			return
+		}
+		notice = "moved"
+		err = h.inTx(r.Context(), func(q *db.Queries) error {
+			return orphans.Move(r.Context(), q, language, wordID, newID)
+		})
+	case "delete":
+		notice = "deleted"
+		err = h.inTx(r.Context(), func(q *db.Queries) error {
+			return orphans.Delete(r.Context(), q, language, wordID)
+		})
+	default:
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't update the saved vocabulary.")
+		return
+	}
+	http.Redirect(w, r, "/admin/lessons?notice="+notice, http.StatusSeeOther)
+}
+
+// LessonEditor handles /admin/lessons/{lang}/{id}, where id is "new" for a
+// lesson that doesn't exist yet, and /admin/lessons/revisions/{id}.json.
+// POST requests carry the whole form plus an action: save, preview, or
+// "add", "remove" or "up" followed by the list entry's path.
+func (h *AdminHandler) LessonEditor(w http.ResponseWriter, r *http.Request) {
+	// parts[0] = "admin", parts[1] = "lessons", parts[2] = lang, parts[3] = id
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 4 {
+		http.NotFound(w, r)
+		return
+	}
+	if parts[2] == "revisions" {
+		h.downloadRevision(w, r, strings.TrimSuffix(parts[3], ".json"))
+		return
+	}
+	langSlug, lessonID := parts[2], parts[3]
+	lang := lessons.GetLanguage(langSlug)
+	if lang == nil {
+		http.NotFound(w, r)
+		return
+	}
+	isNew := lessonID == "new"
+
+	switch r.Method {
+	case http.MethodGet:
+		h.openLesson(w, r, lang, lessonID, isNew)
+	case http.MethodPost:
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+		l, form := parseLessonForm(r.PostForm)
+		if !isNew {
+			l.ID = lessonID // ids are fixed once a lesson exists
+		}
+		switch form.verb() {
+		case "save":
+			h.saveLesson(w, r, lang, l, form, isNew)
+		case "preview":
+			h.previewLesson(w, r, lang, l)
+		default:
+			h.renderLessonEditor(w, r, http.StatusOK, lang, l, isNew, form.errs, "")
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// openLesson shows the editor for the registered lesson, or for its latest
+// revision if that is an unpublished draft or ?revision= asks for one.
+func (h *AdminHandler) openLesson(w http.ResponseWriter, r *http.Request, lang *lessons.Language, lessonID string, isNew bool) {
+	notice := lessonEditorNotices[r.URL.Query().Get("notice")]
+
+	if isNew {
+		l := &lessons.Lesson{Title: "New lesson", Order: 1}
+		if all := lessons.GetAllLessons(lang.Slug); len(all) > 0 {
+			last := all[len(all)-1]
+			l.Order = last.Order + 1
+			l.Prerequisite = &last.ID
+		}
+		h.renderLessonEditor(w, r, http.StatusOK, lang, l, true, nil, notice)
+		return
+	}
+
+	var rev *db.LessonRevision
+	if id := r.URL.Query().Get("revision"); id != "" {
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		found, err := h.queries.GetLessonRevision(r.Context(), n)
+		if err != nil || found.Language != lang.Slug || found.LessonID != lessonID {
+			http.NotFound(w, r)
+			return
+		}
+		rev = &found
+		notice = fmt.Sprintf("Loaded revision #%d. Save to make it the current version.", found.ID)
+	} else {
+		latest, err := h.queries.GetLatestLessonRevision(r.Context(), db.GetLatestLessonRevisionParams{
+			Language: lang.Slug,
+			LessonID: lessonID,
+		})
+		switch {
+		case err == nil && !latest.Published:
+			rev = &latest
+			if notice == "" {
+				notice = fmt.Sprintf("Editing the unpublished draft saved by %s.", latest.Author)
+			}
+		case err != nil && !errors.Is(err, sql.ErrNoRows):
+			serverError(w, r, h.tmpl, err, "We couldn't load this lesson's revisions.")
+			return
+		}
+	}
+
+	var l *lessons.Lesson
+	if rev != nil {
+		var err error
+		if l, err = lessons.Parse(lessonID+".json", []byte(rev.Content)); err != nil {
+			serverError(w, r, h.tmpl, err, "This revision no longer passes validation; download its JSON to fix it by hand.")
+			return
+		}
+	} else {
+		registered := lessons.GetLesson(lang.Slug, lessonID)
+		if registered == nil {
+			http.NotFound(w, r)
+			return
+		}
+		// Registration fills in the language's pass mark; only a lesson's
+		// own one belongs in its file
+		copied := *registered
+		if copied.PassScore == lang.PassScore {
+			copied.PassScore = 0
+		}
+		l = &copied
+	}
+	h.renderLessonEditor(w, r, http.StatusOK, lang, l, false, nil, notice)
+}
+
+// saveLesson validates the edited lesson and, if it passes, writes it to the
+// language's source or stores it as a draft, recording a revision either way.
+func (h *AdminHandler) saveLesson(w http.ResponseWriter, r *http.Request, lang *lessons.Language, l *lessons.Lesson, form *lessonForm, isNew bool) {
+	errs := form.errs
+	file := l.ID + ".json"
+	if existing := lessons.GetLesson(lang.Slug, l.ID); existing != nil && !isNew {
+		file = existing.File
+	}
+
+	if isNew {
+		_, err := h.queries.GetLatestLessonRevision(r.Context(), db.GetLatestLessonRevisionParams{
+			Language: lang.Slug,
+			LessonID: l.ID,
+		})
+		switch {
+		case !lessonIDPattern.MatchString(l.ID) || l.ID == "new":
+			errs = append(errs, &lessons.FieldError{Path: "$.id", Message: "use lowercase letters, digits, dashes and underscores (and not \"new\")"})
+		case lessons.GetLesson(lang.Slug, l.ID) != nil || err == nil:
+			errs = append(errs, &lessons.FieldError{Path: "$.id", Message: fmt.Sprintf("lesson %q already exists", l.ID)})
+		}
+	}
+
+	data, err := lessons.Encode(l)
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't encode the lesson.")
+		return
+	}
+	if len(errs) == 0 {
+		if _, err := lessons.Parse(file, data); err != nil {
+			var fieldErrs lessons.Errors
+			if !errors.As(err, &fieldErrs) {
+				serverError(w, r, h.tmpl, err, "We couldn't validate the lesson.")
+				return
+			}
+			errs = fieldErrs
+		}
+	}
+	if len(errs) > 0 {
+		h.renderLessonEditor(w, r, http.StatusUnprocessableEntity, lang, l, isNew, errs, "")
+		return
+	}
+
+	// The revision is recorded before the live file changes, so published
+	// content always has a revision to roll back to
+	_, published := lessons.GetSource(lang.Slug)
+	author := ""
+	if u := getUser(r.Context(), h.queries, middleware.GetUserID(r.Context())); u != nil {
+		author = u.Username
+	}
+	rev, err := h.queries.CreateLessonRevision(r.Context(), db.CreateLessonRevisionParams{
+		Language:  lang.Slug,
+		LessonID:  l.ID,
+		Content:   string(data),
+		Published: published,
+		Author:    author,
+	})
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't record the revision.")
+		return
+	}
+
+	if published {
+		if err := lessons.SaveLesson(lang.Slug, file, data); err != nil {
+			if delErr := h.queries.DeleteLessonRevision(r.Context(), rev.ID); delErr != nil {
+				slog.Error("withdraw unpublished lesson revision", "id", rev.ID, "error", delErr)
+			}
+			errs = append(errs, &lessons.FieldError{Path: "$", Message: "not saved: " + err.Error()})
+			h.renderLessonEditor(w, r, http.StatusUnprocessableEntity, lang, l, isNew, errs, "")
+			return
+		}
+	}
+
+	notice := "draft"
+	if published {
+		notice = "saved"
+	}
+	http.Redirect(w, r, "/admin/lessons/"+lang.Slug+"/"+l.ID+"?notice="+notice, http.StatusSeeOther)
+}
+
+// previewLesson renders the edited lesson with the learner's lesson page.
+func (h *AdminHandler) previewLesson(w http.ResponseWriter, r *http.Request, lang *lessons.Language, l *lessons.Lesson) {
+	if l.Illustration != "" {
+		l.IllustrationURL = illustrationBase(lang.Slug) + l.Illustration
+	}
+	h.tmpl.Render(w, "lesson.html", map[string]interface{}{
+		"Title":          "Preview: " + l.Title,
+		"Lesson":         l,
+		"Covered":        lessons.Covered(lang.Slug, l),
+		"QuizLength":     len(lessons.ForAttempt(lang.Slug, l, 0).Quiz.Questions),
+		"Preview":        true,
+		"User":           getUser(r.Context(), h.queries, middleware.GetUserID(r.Context())),
+		"LanguageSlug":   lang.Slug,
+		"LanguageName":   lang.DisplayName,
+		"LanguageConfig": lang,
+	})
+}
+
+// illustrationBase returns the URL prefix a language's illustrations are
+// served under, taken from its registered lessons.
+func illustrationBase(slug string) string {
+	for _, l := range lessons.GetAllLessons(slug) {
+		if l.Illustration != "" && strings.HasSuffix(l.IllustrationURL, l.Illustration) {
+			return strings.TrimSuffix(l.IllustrationURL, l.Illustration)
+		}
+	}
+	return "/static/svg/"
+}
+
+func (h *AdminHandler) renderLessonEditor(w http.ResponseWriter, r *http.Request, status int, lang *lessons.Language, l *lessons.Lesson, isNew bool, errs lessons.Errors, notice string) {
+	var revisions []db.LessonRevision
+	if !isNew {
+		var err error
+		revisions, err = h.queries.ListLessonRevisions(r.Context(), db.ListLessonRevisionsParams{
+			Language: lang.Slug,
+			LessonID: l.ID,
+		})
+		if err != nil {
+			serverError(w, r, h.tmpl, err, "We couldn't load this lesson's revisions.")
+			return
+		}
+	}
+
+	// Errors are keyed by path without the leading "$.", like the field names
+	fieldErrors := make(map[string][]string)
+	var errorList []string
+	for _, e := range errs {
+		key := strings.TrimPrefix(strings.TrimPrefix(e.Path, "$"), ".")
+		fieldErrors[key] = append(fieldErrors[key], e.Message)
+		errorList = append(errorList, e.Error())
+	}
+
+	var others []*lessons.Lesson
+	for _, other := range lessons.GetAllLessons(lang.Slug) {
+		if other.ID != l.ID {
+			others = append(others, other)
+		}
+	}
+	var wordIDs []string
+	for _, s := range l.Sections {
+		for _, item := range s.Items {
+			if item.ID != "" {
+				wordIDs = append(wordIDs, item.ID)
+			}
+		}
+	}
+	prerequisite := ""
+	if l.Prerequisite != nil {
+		prerequisite = *l.Prerequisite
+	}
+	generateCount, generating := 0, make(map[string]bool)
+	if g := l.Quiz.Generate; g != nil {
+		generateCount = g.Count
+		for _, typ := range g.Types {
+			generating[typ] = true
+		}
+	}
+	src, editable := lessons.GetSource(lang.Slug)
+
+	title, formPath := "Edit: "+l.Title, "/admin/lessons/"+lang.Slug+"/"+l.ID
+	if isNew {
+		title, formPath = "New lesson", "/admin/lessons/"+lang.Slug+"/new"
+	}
+	h.tmpl.RenderStatus(w, status, "admin_lesson_edit.html", map[string]interface{}{
+		"Title":             title,
+		"User":              getUser(r.Context(), h.queries, middleware.GetUserID(r.Context())),
+		"Lesson":            l,
+		"IsNew":             isNew,
+		"FormPath":          formPath,
+		"LanguageSlug":      lang.Slug,
+		"LanguageConfig":    lang,
+		"Prerequisite":      prerequisite,
+		"OtherLessons":      others,
+		"WordIDs":           wordIDs,
+		"SectionTypes":      lessons.SectionTypes(),
+		"QuestionTypes":     lessons.QuestionTypes(),
+		"GrammarBlockTypes": lessons.GrammarBlockTypes(),
+		"Voices":            lessons.Voices,
+		"GeneratedTypes":    lessons.GeneratedTypes(),
+		"GenerateCount":     generateCount,
+		"Generating":        generating,
+		"DefaultTypes":      strings.Join(lessons.DefaultGeneratedTypes, ", "),
+		"Errors":            fieldErrors,
+		"ErrorList":         errorList,
+		"Notice":            notice,
+		"Editable":          editable,
+		"SourceDir":         src.Dir,
+		"Revisions":         revisions,
+	})
+}
+
+// downloadRevision serves a revision's lesson JSON.
+func (h *AdminHandler) downloadRevision(w http.ResponseWriter, r *http.Request, id string) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	rev, err := h.queries.GetLessonRevision(r.Context(), n)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, rev.LessonID))
+	w.Write([]byte(rev.Content))
+}
+
+// lessonForm reads the lesson editor form.
+type lessonForm struct {
+	values url.Values
+	action []string // verb, then for list edits the entry's path and a type
+	errs   lessons.Errors
+}
+
+func (f *lessonForm) verb() string {
+	if len(f.action) == 0 {
+		return ""
+	}
+	return f.action[0]
+}
+
+func (f *lessonForm) has(path string) bool {
+	_, ok := f.values[path]
+	return ok
+}
+
+// str returns a field's value with surrounding space trimmed and line
+// endings normalized.
+func (f *lessonForm) str(path string) string {
+	return strings.TrimSpace(strings.ReplaceAll(f.values.Get(path), "\r\n", "\n"))
+}
+
+// optional returns nil for an empty field.
+func (f *lessonForm) optional(path string) *string {
+	s := f.str(path)
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func (f *lessonForm) int(path string) int {
+	s := f.str(path)
+	if s == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		f.errorf(path, "want a whole number")
+	}
+	return n
+}
+
+func (f *lessonForm) float(path string) float64 {
+	s := f.str(path)
+	if s == "" {
+		return 0
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		f.errorf(path, "want a number")
+	}
+	return n
+}
+
+// lines splits a textarea into its non-empty lines.
+func (f *lessonForm) lines(path string) []string {
+	var result []string
+	for _, line := range strings.Split(f.str(path), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// cells splits a "|"-separated field into its trimmed cells, keeping empty
+// ones so the rest stay in their columns.
+func (f *lessonForm) cells(path string) []string {
+	s := f.str(path)
+	if s == "" {
+		return nil
+	}
+	cells := strings.Split(s, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// count returns how many entries the list at path has, by the field every
+// entry renders.
+func (f *lessonForm) count(path, key string) int {
+	n := 0
+	for f.has(fmt.Sprintf("%s[%d].%s", path, n, key)) {
+		n++
+	}
+	return n
+}
+
+func (f *lessonForm) errorf(path, format string, args ...any) {
+	f.errs = append(f.errs, &lessons.FieldError{Path: "$." + path, Message: fmt.Sprintf(format, args...)})
+}
+
+// editList applies the form's action to the list at path if it targets it:
+// "add {path} [type]" appends a blank entry, "remove {path}[i]" deletes one
+// and "up {path}[i]" swaps it with the one before.
+func editList[T any](f *lessonForm, path string, items []T, blank func(typ string) T) []T {
+	if len(f.action) < 2 {
+		return items
+	}
+	if f.action[0] == "add" && f.action[1] == path {
+		typ := ""
+		if len(f.action) > 2 {
+			typ = f.action[2]
+		}
+		return append(items, blank(typ))
+	}
+	// The entry is path[i] itself, not something nested inside it
+	index, ok := strings.CutPrefix(f.action[1], path+"[")
+	if !ok || strings.Contains(index, ".") {
+		return items
+	}
+	i, err := strconv.Atoi(strings.TrimSuffix(index, "]"))
+	if err != nil || i < 0 || i >= len(items) {
+		return items
+	}
+	switch f.action[0] {
+	case "remove":
+		return append(items[:i], items[i+1:]...)
+	case "up":
+		if i > 0 {
+			items[i-1], items[i] = items[i], items[i-1]
+		}
+	}
+	return items
+}
+
+// parseLessonForm builds a lesson from the editor form, applying any list
+// edit in its action. Values that can't be parsed are reported in the form's
+// errors.
+func parseLessonForm(values url.Values) (*lessons.Lesson, *lessonForm) {
+	f := &lessonForm{values: values, action: strings.Fields(values.Get("action"))}
+	l := &lessons.Lesson{
+		Schema:       f.str("$schema"),
+		ID:           f.str("id"),
+		Title:        f.str("title"),
+		Description:  f.str("description"),
+		Order:        f.int("order"),
+		Prerequisite: f.optional("prerequisite"),
+		Illustration: f.str("illustration"),
+		PassScore:    f.int("pass_score"),
+		Draft:        f.has("draft"),
+	}
+
+	for i := range f.count("sections", "type") {
+		l.Sections = append(l.Sections, f.section(fmt.Sprintf("sections[%d]", i)))
+	}
+	l.Sections = editList(f, "sections", l.Sections, func(typ string) lessons.Section {
+		if typ == "" {
+			typ = "vocab"
+		}
+		return lessons.Section{Type: typ}
+	})
+
+	for i := range f.count("quiz.questions", "type") {
+		l.Quiz.Questions = append(l.Quiz.Questions, f.question(fmt.Sprintf("quiz.questions[%d]", i)))
+	}
+	l.Quiz.Questions = editList(f, "quiz.questions", l.Quiz.Questions, func(typ string) lessons.Question {
+		if typ == "" {
+			typ = "multiple_choice"
+		}
+		return lessons.Question{Type: typ}
+	})
+
+	// A blank count leaves the quiz as written
+	if f.str("quiz.generate.count") != "" {
+		l.Quiz.Generate = &lessons.Generate{
+			Count: f.int("quiz.generate.count"),
+			Types: f.values["quiz.generate.types"],
+		}
+	}
+	// A blank sample makes an ordinary lesson
+	if f.str("checkpoint.sample") != "" {
+		l.Checkpoint = &lessons.Checkpoint{Sample: f.int("checkpoint.sample")}
+	}
+	return l, f
+}
+
+func (f *lessonForm) section(p string) lessons.Section {
+	s := lessons.Section{
+		Type:        f.str(p + ".type"),
+		Title:       f.str(p + ".title"),
+		Explanation: f.str(p + ".explanation"),
+		Content:     f.str(p + ".content"),
+	}
+	for i := range f.count(p+".items", "id") {
+		s.Items = append(s.Items, f.vocabItem(fmt.Sprintf("%s.items[%d]", p, i)))
+	}
+	s.Items = editList(f, p+".items", s.Items, func(string) lessons.VocabItem { return lessons.VocabItem{} })
+
+	for i := range f.count(p+".examples", "english") {
+		e := fmt.Sprintf("%s.examples[%d]", p, i)
+		s.Examples = append(s.Examples, lessons.Example{
+			English:       f.str(e + ".english"),
+			TargetPrimary: f.str(e + ".target_primary"),
+			TargetAlt:     f.str(e + ".target_alt"),
+		})
+	}
+	s.Examples = editList(f, p+".examples", s.Examples, func(string) lessons.Example { return lessons.Example{} })
+
+	for i := range f.count(p+".blocks", "type") {
+		s.Blocks = append(s.Blocks, f.grammarBlock(fmt.Sprintf("%s.blocks[%d]", p, i)))
+	}
+	s.Blocks = editList(f, p+".blocks", s.Blocks, func(typ string) lessons.GrammarBlock {
+		if typ == "" {
+			typ = "table"
+		}
+		return lessons.GrammarBlock{Type: typ}
+	})
+
+	for i := range f.count(p+".speakers", "id") {
+		sp := fmt.Sprintf("%s.speakers[%d]", p, i)
+		s.Speakers = append(s.Speakers, lessons.Speaker{
+			ID:    f.str(sp + ".id"),
+			Name:  f.str(sp + ".name"),
+			Voice: f.str(sp + ".voice"),
+		})
+	}
+	s.Speakers = editList(f, p+".speakers", s.Speakers, func(string) lessons.Speaker { return lessons.Speaker{} })
+
+	for i := range f.count(p+".lines", "target_primary") {
+		lp := fmt.Sprintf("%s.lines[%d]", p, i)
+		s.Lines = append(s.Lines, lessons.DialogueLine{
+			Speaker:       f.str(lp + ".speaker"),
+			TargetPrimary: f.str(lp + ".target_primary"),
+			TargetAlt:     f.str(lp + ".target_alt"),
+			English:       f.str(lp + ".english"),
+		})
+	}
+	// A new line is said by whoever didn't say the one before it
+	s.Lines = editList(f, p+".lines", s.Lines, func(string) lessons.DialogueLine {
+		var line lessons.DialogueLine
+		if len(s.Speakers) > 0 {
+			line.Speaker = s.Speakers[0].ID
+		}
+		if n := len(s.Lines); n > 0 && len(s.Speakers) > 1 && s.Lines[n-1].Speaker == line.Speaker {
+			line.Speaker = s.Speakers[1].ID
+		}
+		return line
+	})
+	return s
+}
+
+// grammarBlock reads a grammar block. A table's headings and cells are
+// entered as one line each, separated by "|".
+func (f *lessonForm) grammarBlock(p string) lessons.GrammarBlock {
+	b := lessons.GrammarBlock{
+		Type:    f.str(p + ".type"),
+		Title:   f.str(p + ".title"),
+		Headers: f.cells(p + ".headers"),
+	}
+
+	for i := range f.count(p+".rows", "label") {
+		rp := fmt.Sprintf("%s.rows[%d]", p, i)
+		row := lessons.TableRow{Label: f.str(rp + ".label")}
+		alts := f.cells(rp + ".cells_alt")
+		for j, text := range f.cells(rp + ".cells") {
+			cell := lessons.TargetText{TargetPrimary: text}
+			if j < len(alts) {
+				cell.TargetAlt = alts[j]
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		b.Rows = append(b.Rows, row)
+	}
+	b.Rows = editList(f, p+".rows", b.Rows, func(string) lessons.TableRow { return lessons.TableRow{} })
+
+	lemma := lessons.TargetText{
+		TargetPrimary: f.str(p + ".lemma.target_primary"),
+		TargetAlt:     f.str(p + ".lemma.target_alt"),
+	}
+	if lemma != (lessons.TargetText{}) {
+		b.Lemma = &lemma
+	}
+	for i := range f.count(p+".forms", "label") {
+		fp := fmt.Sprintf("%s.forms[%d]", p, i)
+		b.Forms = append(b.Forms, lessons.Form{
+			Label:         f.str(fp + ".label"),
+			TargetPrimary: f.str(fp + ".target_primary"),
+			TargetAlt:     f.str(fp + ".target_alt"),
+			English:       f.str(fp + ".english"),
+		})
+	}
+	b.Forms = editList(f, p+".forms", b.Forms, func(string) lessons.Form { return lessons.Form{} })
+
+	for i := range f.count(p+".words", "target_primary") {
+		wp := fmt.Sprintf("%s.words[%d]", p, i)
+		w := lessons.MorphemeWord{
+			TargetPrimary: f.str(wp + ".target_primary"),
+			TargetAlt:     f.str(wp + ".target_alt"),
+			English:       f.str(wp + ".english"),
+		}
+		for j := range f.count(wp+".parts", "target_primary") {
+			mp := fmt.Sprintf("%s.parts[%d]", wp, j)
+			w.Parts = append(w.Parts, lessons.Morpheme{
+				TargetPrimary: f.str(mp + ".target_primary"),
+				TargetAlt:     f.str(mp + ".target_alt"),
+				Gloss:         f.str(mp + ".gloss"),
+				Highlight:     f.has(mp + ".highlight"),
+			})
+		}
+		w.Parts = editList(f, wp+".parts", w.Parts, func(string) lessons.Morpheme { return lessons.Morpheme{} })
+		b.Words = append(b.Words, w)
+	}
+	b.Words = editList(f, p+".words", b.Words, func(string) lessons.MorphemeWord { return lessons.MorphemeWord{} })
+	return b
+}
+
+func (f *lessonForm) vocabItem(p string) lessons.VocabItem {
+	item := lessons.VocabItem{
+		ID:                f.str(p + ".id"),
+		English:           f.str(p + ".english"),
+		TargetPrimary:     f.str(p + ".target_primary"),
+		TargetAlt:         f.str(p + ".target_alt"),
+		PronunciationHint: f.str(p + ".pronunciation_hint"),
+		AudioOverride:     f.optional(p + ".audio_override"),
+	}
+	ex := lessons.ExampleSentence{
+		English:       f.str(p + ".example_sentence.english"),
+		TargetPrimary: f.str(p + ".example_sentence.target_primary"),
+		TargetAlt:     f.str(p + ".example_sentence.target_alt"),
+	}
+	if ex != (lessons.ExampleSentence{}) {
+		item.ExampleSentence = &ex
+	}
+	return item
+}
+
+func (f *lessonForm) question(p string) lessons.Question {
+	q := lessons.Question{
+		Type:           f.str(p + ".type"),
+		Question:       f.str(p + ".question"),
+		Prompt:         f.str(p + ".prompt"),
+		CorrectAnswers: f.lines(p + ".correct_answers"),
+		WordID:         f.str(p + ".word_id"),
+		Weight:         f.float(p + ".weight"),
+	}
+
+	// Options are rendered with blank inputs to fill in, which are dropped;
+	// the correct radio button holds the input's index, not the option's.
+	correct, picked := f.str(p+".correct"), false
+	for i := 0; f.has(fmt.Sprintf("%s.options[%d]", p, i)); i++ {
+		opt := f.str(fmt.Sprintf("%s.options[%d]", p, i))
+		if opt == "" {
+			continue
+		}
+		if correct == strconv.Itoa(i) {
+			q.Correct, picked = len(q.Options), true
+		}
+		q.Options = append(q.Options, opt)
+	}
+	if !picked && len(q.Options) > 0 && (q.Type == "multiple_choice" || q.Type == "listen_and_choose") {
+		f.errorf(p+".correct", "pick the correct option")
+	}
+
+	for i := range f.count(p+".pairs", "english") {
+		pp := fmt.Sprintf("%s.pairs[%d]", p, i)
+		q.Pairs = append(q.Pairs, lessons.Pair{
+			English: f.str(pp + ".english"),
+			Target:  f.str(pp + ".target"),
+			WordID:  f.str(pp + ".word_id"),
+		})
+	}
+	q.Pairs = editList(f, p+".pairs", q.Pairs, func(string) lessons.Pair { return lessons.Pair{} })
+	return q
+}
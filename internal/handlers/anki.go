@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+
+	"speakeasy/internal/anki"
+	"speakeasy/internal/lessons"
+	"speakeasy/internal/tts"
+)
+
+type AnkiHandler struct {
+	tts  *tts.Client
+	tmpl *TemplateRenderer
+}
+
+func NewAnkiHandler(c *tts.Client, t *TemplateRenderer) *AnkiHandler {
+	return &AnkiHandler{tts: c, tmpl: t}
+}
+
+// LanguageDeck downloads every lesson of a language as one .apkg with a
+// subdeck per lesson.
+func (h *AnkiHandler) LanguageDeck(w http.ResponseWriter, r *http.Request) {
+	langConfig := lessons.GetLanguage(extractLanguage(r.URL.Path))
+	if langConfig == nil {
+		http.NotFound(w, r)
+		return
+	}
+	h.serve(w, r, "speakeasy-"+langConfig.Slug, anki.LanguagePackage(langConfig, h.tts))
+}
+
+// LessonDeck downloads a single lesson's vocabulary as an .apkg. Draft
+// lessons have no deck until they are published, and checkpoints, which
+// teach no words of their own, have none at all.
+func (h *AnkiHandler) LessonDeck(w http.ResponseWriter, r *http.Request) {
+	langConfig := lessons.GetLanguage(extractLanguage(r.URL.Path))
+	if langConfig == nil {
+		http.NotFound(w, r)
+		return
+	}
+	lesson := lessons.GetLesson(langConfig.Slug, extractLessonID(r.URL.Path))
+	if lesson == nil || lesson.Draft || lesson.Checkpoint != nil {
+		http.NotFound(w, r)
+		return
+	}
+	pkg := anki.LessonsPackage(langConfig, []*lessons.Lesson{lesson}, h.tts)
+	h.serve(w, r, "speakeasy-"+langConfig.Slug+"-"+lesson.ID, pkg)
+}
+
+func (h *AnkiHandler) serve(w http.ResponseWriter, r *http.Request, filename string, pkg *anki.Package) {
+	// Build in memory first so a failure can still be reported as a page
+	var buf bytes.Buffer
+	if err := pkg.WriteAPKG(&buf); err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't build your Anki deck. Please try again.")
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.apkg"`)
+	buf.WriteTo(w)
+}
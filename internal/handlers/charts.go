@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The stats page draws its charts as inline SVG in the template. The types
+// here hold precomputed geometry in viewBox units so the template only has
+// to place shapes; all the arithmetic stays in Go.
+
+// Bar is one rectangle of a column or row chart, with its label position.
+type Bar struct {
+	X, Y, Width, Height float64
+	LabelX, LabelY      float64
+	ValueX, ValueY      float64
+	Label               string
+	Value               string
+	Title               string // tooltip
+}
+
+// BarChart is a set of bars in a Width×Height viewBox. Kind is "columns" or
+// "rows" and selects how labels are anchored.
+type BarChart struct {
+	Kind          string
+	Width, Height float64
+	Bars          []Bar
+	Empty         bool
+}
+
+// LineChart is a single score series drawn as a polyline in a 0–100 range.
+type LineChart struct {
+	Width, Height float64
+	Points        []Bar  // X and Y are the point; Title is the tooltip
+	Polyline      string // "x,y x,y ..." for <polyline points>
+	PassY         float64
+}
+
+const (
+	chartPad      = 8.0
+	chartLabelH   = 16.0 // space under columns for their labels
+	chartValueH   = 14.0 // space above columns for their values
+	chartRowH     = 22.0
+	chartRowLabel = 120.0 // width of the label area left of rows
+	chartRowValue = 44.0  // width of the value area right of rows
+)
+
+// columnChart lays out vertical bars, one per value, scaled to the largest.
+func columnChart(labels []string, values []float64, format func(float64) string, titles []string, width, height float64) BarChart {
+	c := BarChart{Kind: "columns", Width: width, Height: height}
+	max := maxValue(values)
+	if max == 0 {
+		c.Empty = true
+	}
+	if len(values) == 0 {
+		return c
+	}
+
+	plotTop := chartValueH
+	plotH := height - chartLabelH - plotTop
+	slot := width / float64(len(values))
+	for i, v := range values {
+		h := 0.0
+		if max > 0 {
+			h = v / max * plotH
+		}
+		x := float64(i) * slot
+		b := Bar{
+			X:      x + slot*0.15,
+			Y:      plotTop + plotH - h,
+			Width:  slot * 0.7,
+			Height: h,
+			LabelX: x + slot/2,
+			LabelY: height - 4,
+			ValueX: x + slot/2,
+			ValueY: plotTop + plotH - h - 3,
+			Label:  labels[i],
+			Value:  format(v),
+		}
+		if i < len(titles) {
+			b.Title = titles[i]
+		}
+		c.Bars = append(c.Bars, b)
+	}
+	return c
+}
+
+// rowChart lays out horizontal bars, one per row, scaled to max (or to the
+// largest value when max is 0). Height grows with the number of rows.
+func rowChart(labels []string, values []float64, format func(float64) string, titles []string, width, max float64) BarChart {
+	if max == 0 {
+		max = maxValue(values)
+	}
+	c := BarChart{Kind: "rows", Width: width, Height: float64(len(values))*chartRowH + chartPad}
+	if len(values) == 0 || max == 0 {
+		c.Empty = true
+		return c
+	}
+
+	plotW := width - chartRowLabel - chartRowValue
+	for i, v := range values {
+		y := chartPad/2 + float64(i)*chartRowH
+		w := v / max * plotW
+		b := Bar{
+			X:      chartRowLabel,
+			Y:      y + 3,
+			Width:  w,
+			Height: chartRowH - 6,
+			LabelX: chartRowLabel - 6,
+			LabelY: y + chartRowH/2 + 4,
+			ValueX: chartRowLabel + w + 4,
+			ValueY: y + chartRowH/2 + 4,
+			Label:  labels[i],
+			Value:  format(v),
+		}
+		if i < len(titles) {
+			b.Title = titles[i]
+		}
+		c.Bars = append(c.Bars, b)
+	}
+	return c
+}
+
+// scoreLine plots percentage scores in order, with a dashed pass mark.
+func scoreLine(scores []int, titles []string, passScore int, width, height float64) LineChart {
+	c := LineChart{Width: width, Height: height, PassY: scoreY(passScore, height)}
+	var pts []string
+	for i, s := range scores {
+		x := width / 2
+		if len(scores) > 1 {
+			x = chartPad + float64(i)*(width-2*chartPad)/float64(len(scores)-1)
+		}
+		p := Bar{X: x, Y: scoreY(s, height)}
+		if i < len(titles) {
+			p.Title = titles[i]
+		}
+		c.Points = append(c.Points, p)
+		pts = append(pts, fmtCoord(p.X)+","+fmtCoord(p.Y))
+	}
+	c.Polyline = strings.Join(pts, " ")
+	return c
+}
+
+func scoreY(score int, height float64) float64 {
+	return chartPad + float64(100-score)/100*(height-2*chartPad)
+}
+
+func maxValue(values []float64) float64 {
+	max := 0.0
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+func fmtCoord(f float64) string {
+	return strconv.FormatFloat(f, 'f', 1, 64)
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// serverError logs err with the request context and renders the friendly
+// error page with a 500 status. message is shown to the user; err is not.
+func serverError(w http.ResponseWriter, r *http.Request, t *TemplateRenderer, err error, message string) {
+	slog.Error(message,
+		"method", r.Method,
+		"path", r.URL.Path,
+		"error", err,
+	)
+	t.RenderStatus(w, http.StatusInternalServerError, "error.html", map[string]interface{}{
+		"Title":   "Error",
+		"Message": message,
+		"BackURL": r.URL.Path,
+	})
+}
@@ -4,27 +4,142 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"html/template"
+	"io/fs"
+	"log/slog"
+	"math"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"speakeasy/internal/activity"
 	"speakeasy/internal/db"
 	"speakeasy/internal/lessons"
+	"speakeasy/internal/markdown"
 	"speakeasy/internal/middleware"
+	"speakeasy/internal/translit"
 )
 
 type TemplateRenderer struct {
+	dir string
+
+	mu        sync.Mutex
 	templates map[string]*template.Template
+	partials  *template.Template
+
+	// Set by ReloadFromDisk: re-parse when the files under dir change
+	reload    bool
+	signature string
 }
 
 func NewTemplateRenderer(templatesDir string) *TemplateRenderer {
+	templates, partials, err := parseTemplates(templatesDir)
+	if err != nil {
+		panic(err)
+	}
+	return &TemplateRenderer{dir: templatesDir, templates: templates, partials: partials}
+}
+
+// ReloadFromDisk makes the renderer re-parse its templates when any file in
+// the templates directory changes, checked on each render. It is used in
+// development mode. If the edited templates fail to parse the error is logged
+// and the previous set keeps serving.
+func (t *TemplateRenderer) ReloadFromDisk() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reload = true
+	t.signature = templatesSignature(t.dir)
+}
+
+// current returns the parsed templates, reloading them first if needed.
+func (t *TemplateRenderer) current() (map[string]*template.Template, *template.Template) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.reload {
+		if sig := templatesSignature(t.dir); sig != t.signature {
+			t.signature = sig
+			templates, partials, err := parseTemplates(t.dir)
+			if err != nil {
+				slog.Error("dev mode: template reload failed, keeping previous version", "error", err)
+			} else {
+				t.templates, t.partials = templates, partials
+				slog.Info("dev mode: reloaded templates")
+			}
+		}
+	}
+	return t.templates, t.partials
+}
+
+// templatesSignature summarizes the name, size and modification time of
+// every file under dir, so adding, removing or editing one changes it.
+func templatesSignature(dir string) string {
+	var sb strings.Builder
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			fmt.Fprintf(&sb, "%s:%d:%d|", path, info.Size(), info.ModTime().UnixNano())
+		}
+		return nil
+	})
+	return sb.String()
+}
+
+// parseTemplates parses every page, each with the layout and all partials,
+// and the partials on their own.
+func parseTemplates(templatesDir string) (map[string]*template.Template, *template.Template, error) {
 	funcMap := template.FuncMap{
 		"add": func(a, b int) int { return a + b },
 		"optionLetter": func(i int) string {
 			return string(rune('A' + i))
 		},
+		// points formats a weighted score without trailing zeros: 2, 1.5, 0.67
+		"points": func(f float64) string {
+			return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+		},
+		"percent": func(f float64) int { return int(math.Round(f * 100)) },
+		"join":    strings.Join,
+		// blanks pads a list with empty strings to at least n entries and
+		// one more, so a form has inputs left to fill in
+		"blanks": func(items []string, n int) []string {
+			padded := append([]string(nil), items...)
+			for len(padded) < n || len(padded) == len(items) {
+				padded = append(padded, "")
+			}
+			return padded
+		},
+		// markdown renders lesson prose written in the markdown package's
+		// subset, with [[...]] spans spoken in the lesson's language
+		"markdown": func(src string, lang *lessons.Language) template.HTML {
+			target := markdown.Target{TTSCode: lang.TTSCode, DualScript: lang.HasDualScript}
+			if toAlt, ok := translit.For(lang.Slug); ok {
+				target.ToAlt = toAlt
+			}
+			return markdown.Render(src, target)
+		},
+		// dict builds a map from alternating keys and values so a partial
+		// can be passed more than one value: {{template "x" dict "A" 1 "B" 2}}
+		"dict": func(kv ...interface{}) (map[string]interface{}, error) {
+			if len(kv)%2 != 0 {
+				return nil, fmt.Errorf("dict: odd number of arguments")
+			}
+			m := make(map[string]interface{}, len(kv)/2)
+			for i := 0; i < len(kv); i += 2 {
+				key, ok := kv[i].(string)
+				if !ok {
+					return nil, fmt.Errorf("dict: key %v is not a string", kv[i])
+				}
+				m[key] = kv[i+1]
+			}
+			return m, nil
+		},
 	}
 
 	layoutFile := filepath.Join(templatesDir, "layout.html")
@@ -34,23 +149,52 @@ func NewTemplateRenderer(templatesDir string) *TemplateRenderer {
 		"lesson.html",
 		"lesson_list.html",
 		"quiz.html",
+		"quiz_step.html",
 		"results.html",
+		"placement.html",
+		"stats.html",
+		"words.html",
 		"birthday.html",
+		"admin.html",
+		"admin_questions.html",
+		"admin_lessons.html",
+		"admin_lesson_edit.html",
+		"error.html",
 	}
 
+	// Partials are fragments shared by pages and returned on their own to
+	// htmx requests. Every page can {{template}} any of them.
+	partialFiles, _ := filepath.Glob(filepath.Join(templatesDir, "partials", "*.html"))
+
 	templates := make(map[string]*template.Template)
 	for _, page := range pages {
-		tmpl := template.Must(
-			template.New("").Funcs(funcMap).ParseFiles(layoutFile, filepath.Join(templatesDir, page)),
-		)
+		files := append([]string{layoutFile, filepath.Join(templatesDir, page)}, partialFiles...)
+		tmpl, err := template.New("").Funcs(funcMap).ParseFiles(files...)
+		if err != nil {
+			return nil, nil, err
+		}
 		templates[page] = tmpl
 	}
 
-	return &TemplateRenderer{templates: templates}
+	partials := template.New("").Funcs(funcMap)
+	if len(partialFiles) > 0 {
+		var err error
+		if partials, err = partials.ParseFiles(partialFiles...); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return templates, partials, nil
 }
 
 func (t *TemplateRenderer) Render(w http.ResponseWriter, name string, data interface{}) {
-	tmpl, ok := t.templates[name]
+	t.RenderStatus(w, http.StatusOK, name, data)
+}
+
+// RenderStatus renders a page like Render but with the given HTTP status code.
+func (t *TemplateRenderer) RenderStatus(w http.ResponseWriter, status int, name string, data interface{}) {
+	templates, _ := t.current()
+	tmpl, ok := templates[name]
 	if !ok {
 		http.Error(w, "template not found: "+name, http.StatusInternalServerError)
 		return
@@ -61,6 +205,24 @@ func (t *TemplateRenderer) Render(w http.ResponseWriter, name string, data inter
 		return
 	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+// RenderPartial renders a single named template from the partials directory,
+// without the layout. It is used to answer htmx requests.
+func (t *TemplateRenderer) RenderPartial(w http.ResponseWriter, name string, data interface{}) {
+	_, partials := t.current()
+	if partials.Lookup(name) == nil {
+		http.Error(w, "partial not found: "+name, http.StatusInternalServerError)
+		return
+	}
+	var buf bytes.Buffer
+	if err := partials.ExecuteTemplate(&buf, name, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	buf.WriteTo(w)
 }
 
@@ -74,13 +236,20 @@ func NewLessonHandler(q *db.Queries, t *TemplateRenderer) *LessonHandler {
 }
 
 type LessonListItem struct {
-	ID           string
-	Title        string
-	Description  string
-	Order        int
-	Illustration string
-	Status       string
-	BestScore    int64
+	ID              string
+	Title           string
+	Description     string
+	Order           int
+	IllustrationURL string
+	Status          string
+	BestScore       int64
+	Placed          bool // completed by a placement test rather than its quiz
+	Draft           bool // shown to admins only
+
+	// Checkpoints test the lessons before them and gate the next unit
+	Checkpoint bool
+	Covers     string // e.g. "Lessons 1–5"
+	PassScore  int
 }
 
 type LanguageSummary struct {
@@ -100,7 +269,7 @@ func (h *LessonHandler) Home(w http.ResponseWriter, r *http.Request) {
 			summaries = append(summaries, LanguageSummary{
 				Slug:        lang.Slug,
 				DisplayName: lang.DisplayName,
-				Total:       len(lessons.GetAllLessons(lang.Slug)),
+				Total:       len(lessons.GetLessons(lang.Slug, false)),
 			})
 		}
 		h.tmpl.Render(w, "home.html", map[string]interface{}{
@@ -128,11 +297,14 @@ func (h *LessonHandler) Home(w http.ResponseWriter, r *http.Request) {
 	totalCompletedForAvg := int64(0)
 
 	for _, lang := range allLanguages {
-		langLessons := lessons.GetAllLessons(lang.Slug)
-		completed, _ := h.queries.CountCompletedLessons(r.Context(), db.CountCompletedLessonsParams{
+		langLessons := lessons.GetLessons(lang.Slug, false)
+		completed, err := h.queries.CountCompletedLessons(r.Context(), db.CountCompletedLessonsParams{
 			UserID:   userID,
 			Language: lang.Slug,
 		})
+		if err != nil {
+			slog.Error("dashboard: count completed lessons", "language", lang.Slug, "error", err)
+		}
 
 		total := len(langLessons)
 		pct := 0
@@ -152,20 +324,26 @@ func (h *LessonHandler) Home(w http.ResponseWriter, r *http.Request) {
 		totalLessons += total
 
 		if completed > 0 {
-			score, _ := h.queries.GetTotalScore(r.Context(), db.GetTotalScoreParams{
+			score, err := h.queries.GetTotalScore(r.Context(), db.GetTotalScoreParams{
 				UserID:   userID,
 				Language: lang.Slug,
 			})
+			if err != nil {
+				slog.Error("dashboard: total score", "language", lang.Slug, "error", err)
+			}
 			if ts, ok := score.(int64); ok {
 				totalScoreSum += ts
 				totalCompletedForAvg += completed
 			}
 		}
 
-		vocabProgress, _ := h.queries.GetVocabProgress(r.Context(), db.GetVocabProgressParams{
+		vocabProgress, err := h.queries.GetVocabProgress(r.Context(), db.GetVocabProgressParams{
 			UserID:   userID,
 			Language: lang.Slug,
 		})
+		if err != nil {
+			slog.Error("dashboard: vocab progress", "language", lang.Slug, "error", err)
+		}
 		for _, vp := range vocabProgress {
 			if vp.MasteryLevel.Valid && vp.MasteryLevel.Int64 >= 1 {
 				totalWordsLearned++
@@ -183,6 +361,27 @@ func (h *LessonHandler) Home(w http.ResponseWriter, r *http.Request) {
 		avgScore = int(totalScoreSum) / int(totalCompletedForAvg)
 	}
 
+	streak, heatmap, err := userActivity(r.Context(), h.queries, &user)
+	if err != nil {
+		slog.Error("dashboard: activity", "error", err)
+	}
+	badges, err := badgeViews(r.Context(), h.queries, userID)
+	if err != nil {
+		slog.Error("dashboard: badges", "error", err)
+	}
+	var newBadges []BadgeView
+	for _, b := range badges {
+		if b.New {
+			newBadges = append(newBadges, b)
+		}
+	}
+	if len(newBadges) > 0 {
+		// Announce each badge once
+		if err := h.queries.MarkAchievementsSeen(r.Context(), userID); err != nil {
+			slog.Error("dashboard: mark badges seen", "error", err)
+		}
+	}
+
 	h.tmpl.Render(w, "home.html", map[string]interface{}{
 		"Title":            "Dashboard",
 		"User":             user,
@@ -193,6 +392,11 @@ func (h *LessonHandler) Home(w http.ResponseWriter, r *http.Request) {
 		"ProgressPercent":  progressPercent,
 		"AvgScore":         avgScore,
 		"WordsLearned":     totalWordsLearned,
+		"Streak":           streak,
+		"Heatmap":          heatmap,
+		"GoalOptions":      activity.GoalOptions,
+		"Badges":           badges,
+		"NewBadges":        newBadges,
 	})
 }
 
@@ -206,7 +410,8 @@ func (h *LessonHandler) LessonList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	allLessons := lessons.GetAllLessons(langSlug)
+	user := getUser(r.Context(), h.queries, userID)
+	allLessons := lessons.GetLessons(langSlug, canSeeDrafts(user))
 
 	progressList, _ := h.queries.ListLessonProgress(r.Context(), db.ListLessonProgressParams{
 		UserID:   userID,
@@ -219,34 +424,50 @@ func (h *LessonHandler) LessonList(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var lessonItems []LessonListItem
+	prevCompleted := false
 	for _, l := range allLessons {
-		status := "locked"
+		status := string(lessons.InitialStatus(l))
+		if prevCompleted {
+			// As in initialStatus: the lesson after a completed one is open
+			status = string(lessons.StatusAvailable)
+		}
 		var bestScore int64
+		placed := false
 		if p, ok := progressMap[l.ID]; ok {
 			status = p.Status
+			placed = p.Placed
 			if p.BestScore.Valid {
 				bestScore = p.BestScore.Int64
 			}
-		} else if l.Order == 1 {
-			status = "available"
+		}
+		if !l.Draft {
+			prevCompleted = status == string(lessons.StatusCompleted)
 		}
 
-		lessonItems = append(lessonItems, LessonListItem{
-			ID:           l.ID,
-			Title:        l.Title,
-			Description:  l.Description,
-			Order:        l.Order,
-			Illustration: l.Illustration,
-			Status:       status,
-			BestScore:    bestScore,
-		})
+		item := LessonListItem{
+			ID:              l.ID,
+			Title:           l.Title,
+			Description:     l.Description,
+			Order:           l.Order,
+			IllustrationURL: l.IllustrationURL,
+			Status:          status,
+			BestScore:       bestScore,
+			Placed:          placed,
+			Draft:           l.Draft,
+		}
+		if l.Checkpoint != nil {
+			item.Checkpoint = true
+			item.Covers = coverage(lessons.Covered(langSlug, l))
+			item.PassScore = l.PassScore
+		}
+		lessonItems = append(lessonItems, item)
 	}
 
 	completed, _ := h.queries.CountCompletedLessons(r.Context(), db.CountCompletedLessonsParams{
 		UserID:   userID,
 		Language: langSlug,
 	})
-	totalLessons := len(allLessons)
+	totalLessons := len(lessons.GetLessons(langSlug, false))
 	progressPercent := 0
 	if totalLessons > 0 {
 		progressPercent = int(completed) * 100 / totalLessons
@@ -256,7 +477,8 @@ func (h *LessonHandler) LessonList(w http.ResponseWriter, r *http.Request) {
 		"Title":           langConfig.DisplayName + " Lessons",
 		"Lessons":         lessonItems,
 		"ProgressPercent": progressPercent,
-		"User":            getUser(r.Context(), h.queries, userID),
+		"Completed":       completed,
+		"User":            user,
 		"LanguageSlug":    langSlug,
 		"LanguageName":    langConfig.DisplayName,
 		"LanguageConfig":  langConfig,
@@ -274,32 +496,77 @@ func (h *LessonHandler) LessonView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	lesson := lessons.GetLesson(langSlug, lessonID)
+	user := getUser(r.Context(), h.queries, userID)
+	lesson := visibleLesson(langSlug, lessonID, user)
 	if lesson == nil {
 		http.NotFound(w, r)
 		return
 	}
 
-	// Mark as in_progress
-	now := sql.NullTime{Time: time.Now(), Valid: true}
-	h.queries.UpsertLessonProgress(r.Context(), db.UpsertLessonProgressParams{
-		UserID:       userID,
-		Language:     langSlug,
-		LessonID:     lessonID,
-		Status:       "in_progress",
-		LastAccessed: now,
-	})
+	status, err := lessonStatus(r.Context(), h.queries, userID, langSlug, lesson)
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't load your progress for this lesson.")
+		return
+	}
+	if !status.Accessible() && !lesson.Draft {
+		http.Redirect(w, r, "/lessons/"+langSlug, http.StatusSeeOther)
+		return
+	}
+
+	// Opening an available lesson starts it; the view is recorded either way
+	viewed := status.Next(lessons.EventView)
+	if _, err := h.queries.RecordLessonView(r.Context(), db.RecordLessonViewParams{
+		UserID:     userID,
+		Language:   langSlug,
+		LessonID:   lessonID,
+		Status:     string(viewed),
+		StatusRank: int64(viewed.Rank()),
+		ViewedAt:   sql.NullTime{Time: time.Now(), Valid: true},
+	}); err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't save your progress for this lesson.")
+		return
+	}
 
 	h.tmpl.Render(w, "lesson.html", map[string]interface{}{
 		"Title":          lesson.Title,
 		"Lesson":         lesson,
-		"User":           getUser(r.Context(), h.queries, userID),
+		"Covered":        lessons.Covered(langSlug, lesson),
+		"QuizLength":     len(lessons.ForAttempt(langSlug, lesson, 0).Quiz.Questions),
+		"User":           user,
 		"LanguageSlug":   langSlug,
 		"LanguageName":   langConfig.DisplayName,
 		"LanguageConfig": langConfig,
 	})
 }
 
+// coverage describes the lessons a checkpoint covers by their numbers, e.g.
+// "Lessons 1–5", or "Lesson 3" for just one.
+func coverage(covered []*lessons.Lesson) string {
+	switch len(covered) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("Lesson %d", covered[0].Order)
+	}
+	return fmt.Sprintf("Lessons %d–%d", covered[0].Order, covered[len(covered)-1].Order)
+}
+
+// canSeeDrafts reports whether user may see draft lessons: only admins can.
+func canSeeDrafts(user *db.User) bool {
+	return user != nil && user.IsAdmin
+}
+
+// visibleLesson returns a lesson, or nil if it doesn't exist or is a draft
+// user may not see. Admins can open drafts whatever their progress, so
+// status checks should let drafts through.
+func visibleLesson(langSlug, lessonID string, user *db.User) *lessons.Lesson {
+	l := lessons.GetLesson(langSlug, lessonID)
+	if l == nil || (l.Draft && !canSeeDrafts(user)) {
+		return nil
+	}
+	return l
+}
+
 // extractLanguage extracts the language slug from a URL path like /lessons/serbian/...
 func extractLanguage(path string) string {
 	parts := strings.Split(strings.Trim(path, "/"), "/")
@@ -329,18 +596,60 @@ func getUser(ctx context.Context, q *db.Queries, userID int64) *db.User {
 	return &user
 }
 
+// lessonStatus returns the user's status for a lesson, falling back to the
+// lesson's initial status when no progress row exists yet.
+func lessonStatus(ctx context.Context, q *db.Queries, userID int64, langSlug string, l *lessons.Lesson) (lessons.Status, error) {
+	p, err := q.GetLessonProgress(ctx, db.GetLessonProgressParams{
+		UserID:   userID,
+		Language: langSlug,
+		LessonID: l.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return initialStatus(ctx, q, userID, langSlug, l)
+	}
+	if err != nil {
+		return "", err
+	}
+	return lessons.Status(p.Status), nil
+}
+
+// initialStatus is the status of a lesson the user has no progress row for.
+// Besides the first lesson, a lesson whose predecessor is completed is open:
+// one added after the user passed the lesson before it, such as a new
+// checkpoint, would otherwise stay locked for good.
+func initialStatus(ctx context.Context, q *db.Queries, userID int64, langSlug string, l *lessons.Lesson) (lessons.Status, error) {
+	status := lessons.InitialStatus(l)
+	prevID := lessons.GetPreviousLessonID(langSlug, l.ID)
+	if status != lessons.StatusLocked || prevID == "" {
+		return status, nil
+	}
+	prev, err := q.GetLessonProgress(ctx, db.GetLessonProgressParams{
+		UserID:   userID,
+		Language: langSlug,
+		LessonID: prevID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return status, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if lessons.Status(prev.Status) == lessons.StatusCompleted {
+		return lessons.StatusAvailable, nil
+	}
+	return status, nil
+}
+
 func initLessonProgress(ctx context.Context, q *db.Queries, userID int64, langSlug string) {
-	allLessons := lessons.GetAllLessons(langSlug)
+	allLessons := lessons.GetLessons(langSlug, false)
 	for _, l := range allLessons {
-		status := "locked"
-		if l.Order == 1 {
-			status = "available"
-		}
+		status := lessons.InitialStatus(l)
 		q.UpsertLessonProgress(ctx, db.UpsertLessonProgressParams{
-			UserID:   userID,
-			Language: langSlug,
-			LessonID: l.ID,
-			Status:   status,
+			UserID:     userID,
+			Language:   langSlug,
+			LessonID:   l.ID,
+			Status:     string(status),
+			StatusRank: int64(status.Rank()),
 		})
 	}
 }
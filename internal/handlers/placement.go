@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"speakeasy/internal/db"
+	"speakeasy/internal/lessons"
+	"speakeasy/internal/middleware"
+)
+
+// PlacementPage serves a language's placement test: an introduction until
+// the user starts one, then the questions of the stage they are on.
+func (h *QuizHandler) PlacementPage(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	langConfig := lessons.GetLanguage(extractLanguage(r.URL.Path))
+	if langConfig == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	test, err := h.queries.GetOpenPlacementTest(r.Context(), db.GetOpenPlacementTestParams{
+		UserID:   userID,
+		Language: langConfig.Slug,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		h.renderPlacement(w, r, userID, langConfig, map[string]interface{}{
+			"Stages": len(lessons.PlacementLessons(langConfig.Slug)),
+		})
+		return
+	}
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't load your placement test.")
+		return
+	}
+	h.renderPlacementStage(w, r, userID, langConfig, test, false)
+}
+
+// StartPlacement starts a new placement test, abandoning any unfinished one.
+func (h *QuizHandler) StartPlacement(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	langConfig := lessons.GetLanguage(extractLanguage(r.URL.Path))
+	if langConfig == nil {
+		http.NotFound(w, r)
+		return
+	}
+	ctx := r.Context()
+
+	open, err := h.queries.GetOpenPlacementTest(ctx, db.GetOpenPlacementTestParams{
+		UserID:   userID,
+		Language: langConfig.Slug,
+	})
+	if err == nil {
+		_, err = h.queries.FinishPlacementTest(ctx, db.FinishPlacementTestParams{
+			FinishedAt: sql.NullTime{Time: time.Now(), Valid: true},
+			ID:         open.ID,
+		})
+	}
+	if err == nil || errors.Is(err, sql.ErrNoRows) {
+		_, err = h.queries.CreatePlacementTest(ctx, db.CreatePlacementTestParams{
+			UserID:   userID,
+			Language: langConfig.Slug,
+			Seed:     rand.Int64(),
+		})
+	}
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't start your placement test. Please try again.")
+		return
+	}
+	http.Redirect(w, r, "/lessons/"+langConfig.Slug+"/placement", http.StatusSeeOther)
+}
+
+// AnswerPlacement grades the answers to the current stage. Passing it moves
+// the test on to the next lesson's stage; failing it, or passing the last
+// one, ends the test and places the user.
+func (h *QuizHandler) AnswerPlacement(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	langConfig := lessons.GetLanguage(extractLanguage(r.URL.Path))
+	if langConfig == nil {
+		http.NotFound(w, r)
+		return
+	}
+	langSlug := langConfig.Slug
+	pageURL := "/lessons/" + langSlug + "/placement"
+
+	test, err := h.queries.GetOpenPlacementTest(r.Context(), db.GetOpenPlacementTestParams{
+		UserID:   userID,
+		Language: langSlug,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Redirect(w, r, pageURL, http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't load your placement test.")
+		return
+	}
+
+	r.ParseForm()
+
+	// A resubmitted stage has already been graded
+	if r.FormValue("stage") != strconv.FormatInt(test.Stage, 10) {
+		http.Redirect(w, r, pageURL, http.StatusSeeOther)
+		return
+	}
+	stages := lessons.PlacementLessons(langSlug)
+	if int(test.Stage) >= len(stages) {
+		h.finishPlacement(w, r, userID, langConfig, test, stages)
+		return
+	}
+	lesson := stages[test.Stage]
+	if v := r.FormValue("lesson_version"); v != "" && v != lesson.Version {
+		h.renderPlacementStage(w, r, userID, langConfig, test, true)
+		return
+	}
+
+	questions := lessons.PlacementQuestions(langSlug, lesson, int(test.Stage), uint64(test.Seed))
+	correct := 0
+	for i, q := range questions {
+		if gradeQuestion(q, r.FormValue("answer-"+strconv.Itoa(i))) {
+			correct++
+		}
+	}
+
+	passed := stagePassed(correct, len(questions))
+	if passed && int(test.Stage)+1 < len(stages) {
+		if _, err := h.queries.AdvancePlacementTest(r.Context(), db.AdvancePlacementTestParams{
+			ID:    test.ID,
+			Stage: test.Stage,
+		}); err != nil {
+			serverError(w, r, h.tmpl, err, "We couldn't save your answers. Please try again.")
+			return
+		}
+		http.Redirect(w, r, pageURL, http.StatusSeeOther)
+		return
+	}
+
+	placed := int(test.Stage)
+	if passed {
+		placed++
+	}
+	h.finishPlacement(w, r, userID, langConfig, test, stages[:placed])
+}
+
+// stagePassed reports whether a placement stage was answered well enough to
+// go on to a harder lesson: at least two thirds of its questions right.
+func stagePassed(correct, asked int) bool {
+	return asked > 0 && correct*3 >= asked*2
+}
+
+// finishPlacement ends a placement test that passed the given lessons and
+// shows where the user was placed.
+func (h *QuizHandler) finishPlacement(w http.ResponseWriter, r *http.Request, userID int64, langConfig *lessons.Language, test db.PlacementTest, passed []*lessons.Lesson) {
+	next, err := h.place(r.Context(), userID, langConfig.Slug, test, passed)
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't save your placement. Please try again.")
+		return
+	}
+	h.renderPlacement(w, r, userID, langConfig, map[string]interface{}{
+		"Finished": true,
+		"Passed":   passed,
+		"Next":     next,
+	})
+}
+
+// place closes a placement test, marking every lesson up to the last one
+// passed as completed by placement (checkpoints in between included) and
+// unlocking the lesson after it, which it returns. Nothing is placed when
+// the first stage was failed, and lessons already completed are left as
+// they are.
+func (h *QuizHandler) place(ctx context.Context, userID int64, langSlug string, test db.PlacementTest, passed []*lessons.Lesson) (*lessons.Lesson, error) {
+	tx, err := h.database.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+
+	now := time.Now()
+	var next *lessons.Lesson
+	if len(passed) > 0 {
+		last := passed[len(passed)-1]
+		for _, l := range lessons.GetLessons(langSlug, false) {
+			if l.Order > last.Order {
+				break
+			}
+			if err := qtx.PlaceLesson(ctx, db.PlaceLessonParams{
+				UserID:      userID,
+				Language:    langSlug,
+				LessonID:    l.ID,
+				StatusRank:  int64(lessons.StatusCompleted.Rank()),
+				CompletedAt: sql.NullTime{Time: now, Valid: true},
+			}); err != nil {
+				return nil, fmt.Errorf("place lesson %s: %w", l.ID, err)
+			}
+		}
+		if nextID := lessons.GetNextLessonID(langSlug, last.ID); nextID != "" {
+			if err := qtx.UnlockLesson(ctx, db.UnlockLessonParams{
+				UserID:     userID,
+				Language:   langSlug,
+				LessonID:   nextID,
+				StatusRank: int64(lessons.StatusAvailable.Rank()),
+			}); err != nil {
+				return nil, fmt.Errorf("unlock next lesson: %w", err)
+			}
+			next = lessons.GetLesson(langSlug, nextID)
+		}
+	}
+
+	if _, err := qtx.FinishPlacementTest(ctx, db.FinishPlacementTestParams{
+		FinishedAt: sql.NullTime{Time: now, Valid: true},
+		ID:         test.ID,
+	}); err != nil {
+		return nil, fmt.Errorf("finish placement test: %w", err)
+	}
+	return next, tx.Commit()
+}
+
+// renderPlacementStage shows the questions of the stage test is on. A test
+// whose stages ran out, because lessons were unpublished since it started,
+// asks the user to finish it, which places them from the POST that follows.
+func (h *QuizHandler) renderPlacementStage(w http.ResponseWriter, r *http.Request, userID int64, langConfig *lessons.Language, test db.PlacementTest, changed bool) {
+	stages := lessons.PlacementLessons(langConfig.Slug)
+	if int(test.Stage) >= len(stages) {
+		h.renderPlacement(w, r, userID, langConfig, map[string]interface{}{
+			"Stage":       int(test.Stage),
+			"Stages":      len(stages),
+			"StagesEnded": true,
+		})
+		return
+	}
+	lesson := stages[test.Stage]
+	h.renderPlacement(w, r, userID, langConfig, map[string]interface{}{
+		"Stage":     int(test.Stage),
+		"Stages":    len(stages),
+		"Percent":   int(test.Stage) * 100 / len(stages),
+		"Lesson":    lesson,
+		"Questions": lessons.PlacementQuestions(langConfig.Slug, lesson, int(test.Stage), uint64(test.Seed)),
+		"Changed":   changed,
+	})
+}
+
+func (h *QuizHandler) renderPlacement(w http.ResponseWriter, r *http.Request, userID int64, langConfig *lessons.Language, data map[string]interface{}) {
+	data["Title"] = langConfig.DisplayName + " Placement Test"
+	data["StageSize"] = lessons.PlacementStageSize
+	data["User"] = getUser(r.Context(), h.queries, userID)
+	data["LanguageSlug"] = langConfig.Slug
+	data["LanguageName"] = langConfig.DisplayName
+	data["LanguageConfig"] = langConfig
+	h.tmpl.Render(w, "placement.html", data)
+}
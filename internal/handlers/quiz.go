@@ -1,49 +1,126 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"speakeasy/internal/achievements"
+	"speakeasy/internal/activity"
 	"speakeasy/internal/db"
 	"speakeasy/internal/lessons"
 	"speakeasy/internal/middleware"
 )
 
 type QuizHandler struct {
-	queries *db.Queries
-	tmpl    *TemplateRenderer
+	database *sql.DB
+	queries  *db.Queries
+	tmpl     *TemplateRenderer
 }
 
-func NewQuizHandler(q *db.Queries, t *TemplateRenderer) *QuizHandler {
-	return &QuizHandler{queries: q, tmpl: t}
+func NewQuizHandler(database *sql.DB, q *db.Queries, t *TemplateRenderer) *QuizHandler {
+	return &QuizHandler{database: database, queries: q, tmpl: t}
 }
 
-func (h *QuizHandler) QuizPage(w http.ResponseWriter, r *http.Request) {
-	userID := middleware.GetUserID(r.Context())
+// quizResult is the graded outcome of one quiz submission.
+type quizResult struct {
+	UserID    int64
+	Language  string
+	LessonID  string
+	Version   string  // the lesson's Version when it was graded
+	Score     int     // weighted percentage
+	Total     int     // questions graded
+	Correct   int     // questions answered fully correctly
+	Earned    float64 // weighted points earned
+	Possible  float64 // weighted points available
+	PassScore int
+	Passed    bool
+	Questions []questionResult
+	Vocab     []vocabResult
+	Badges    []achievements.Badge // newly earned by this submission
+}
+
+// questionResult is one question's line in the results breakdown.
+type questionResult struct {
+	Number int
+	Type   string
+	Weight float64
+	Credit float64 // fraction answered correctly, 0 to 1
+	Points float64 // Credit × Weight
+	Answer string  // as submitted, kept for item analysis
+}
+
+// vocabResult records whether a question tied to a vocab word was answered correctly.
+type vocabResult struct {
+	WordID  string
+	Correct bool
+}
+
+// quizLesson resolves the language and lesson from the URL and checks the
+// user may take its quiz. On failure it has already written the response.
+func (h *QuizHandler) quizLesson(w http.ResponseWriter, r *http.Request, userID int64) (*lessons.Language, *lessons.Lesson, bool) {
 	langSlug := extractLanguage(r.URL.Path)
 	lessonID := extractLessonID(r.URL.Path)
 
 	langConfig := lessons.GetLanguage(langSlug)
 	if langConfig == nil {
 		http.NotFound(w, r)
-		return
+		return nil, nil, false
 	}
 
-	lesson := lessons.GetLesson(langSlug, lessonID)
+	lesson := visibleLesson(langSlug, lessonID, getUser(r.Context(), h.queries, userID))
 	if lesson == nil {
 		http.NotFound(w, r)
+		return nil, nil, false
+	}
+
+	status, err := lessonStatus(r.Context(), h.queries, userID, langSlug, lesson)
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't load your progress for this lesson.")
+		return nil, nil, false
+	}
+	if !status.Accessible() && !lesson.Draft {
+		http.Redirect(w, r, "/lessons/"+langSlug, http.StatusSeeOther)
+		return nil, nil, false
+	}
+	return langConfig, lesson, true
+}
+
+func (h *QuizHandler) QuizPage(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	langConfig, lesson, ok := h.quizLesson(w, r, userID)
+	if !ok {
 		return
 	}
+	h.renderQuiz(w, r, userID, langConfig, lesson, false)
+}
 
+// renderQuiz shows the full quiz form. changed says the lesson was edited
+// while the user was answering an earlier version of it. Generated and
+// sampled questions come from the seed of the user's open quiz session, the
+// same one step-by-step mode uses, so the submission is graded against the
+// questions shown without trusting a seed sent back by the browser.
+func (h *QuizHandler) renderQuiz(w http.ResponseWriter, r *http.Request, userID int64, langConfig *lessons.Language, lesson *lessons.Lesson, changed bool) {
+	session, restarted, err := h.openQuizSession(r.Context(), userID, langConfig.Slug, lesson)
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't start your quiz. Please try again.")
+		return
+	}
 	h.tmpl.Render(w, "quiz.html", map[string]interface{}{
 		"Title":          "Quiz: " + lesson.Title,
-		"Lesson":         lesson,
+		"Lesson":         sessionQuiz(langConfig.Slug, lesson, session),
+		"Version":        lessons.QuizVersion(langConfig.Slug, lesson),
+		"Changed":        changed || restarted,
 		"User":           getUser(r.Context(), h.queries, userID),
-		"LanguageSlug":   langSlug,
+		"LanguageSlug":   langConfig.Slug,
 		"LanguageName":   langConfig.DisplayName,
 		"LanguageConfig": langConfig,
 	})
@@ -51,153 +128,327 @@ func (h *QuizHandler) QuizPage(w http.ResponseWriter, r *http.Request) {
 
 func (h *QuizHandler) SubmitQuiz(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r.Context())
-	langSlug := extractLanguage(r.URL.Path)
-	lessonID := extractLessonID(r.URL.Path)
+	langConfig, lesson, ok := h.quizLesson(w, r, userID)
+	if !ok {
+		return
+	}
+	langSlug := langConfig.Slug
 
-	langConfig := lessons.GetLanguage(langSlug)
-	if langConfig == nil {
-		http.NotFound(w, r)
+	r.ParseForm()
+
+	// Answers to an earlier version of the questions can't be graded against
+	// this one
+	if v := r.FormValue("lesson_version"); v != "" && v != lessons.QuizVersion(langSlug, lesson) {
+		h.renderQuiz(w, r, userID, langConfig, lesson, true)
 		return
 	}
 
-	lesson := lessons.GetLesson(langSlug, lessonID)
-	if lesson == nil {
-		http.NotFound(w, r)
+	res, err := h.submitQuizSession(r.Context(), userID, langSlug, lesson, func(i int) string {
+		return r.FormValue("answer-" + strconv.Itoa(i))
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// Already submitted, e.g. a double-clicked "Submit Quiz", or never
+		// started from the quiz page
+		http.Redirect(w, r, "/lessons/"+langSlug+"/"+lesson.ID, http.StatusSeeOther)
+		return
+	}
+	if errors.Is(err, errQuizChanged) {
+		h.renderQuiz(w, r, userID, langConfig, lesson, true)
+		return
+	}
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't save your quiz result. Please try again.")
 		return
 	}
 
-	r.ParseForm()
+	h.renderResults(w, r, lesson, langConfig, res)
+}
 
-	totalStr := r.FormValue("total")
-	total, _ := strconv.Atoi(totalStr)
-	if total == 0 {
-		total = len(lesson.Quiz.Questions)
+// submitQuizSession grades a full-form submission against the questions of
+// the user's open quiz session, records the result and closes the session,
+// all in one transaction.
+func (h *QuizHandler) submitQuizSession(ctx context.Context, userID int64, langSlug string, lesson *lessons.Lesson, answerFor func(i int) string) (quizResult, error) {
+	tx, err := h.database.BeginTx(ctx, nil)
+	if err != nil {
+		return quizResult{}, fmt.Errorf("begin transaction: %w", err)
 	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
 
-	correct := 0
-	for i := 0; i < total; i++ {
-		if i >= len(lesson.Quiz.Questions) {
-			break
-		}
-		q := lesson.Quiz.Questions[i]
-		answer := r.FormValue("answer-" + strconv.Itoa(i))
-
-		switch q.Type {
-		case "multiple_choice", "listen_and_choose":
-			idx, err := strconv.Atoi(answer)
-			if err == nil && idx == q.Correct {
-				correct++
-				updateVocabCorrect(r, h.queries, userID, langSlug, q, true)
-			} else {
-				updateVocabCorrect(r, h.queries, userID, langSlug, q, false)
-			}
+	session, err := qtx.GetOpenQuizSession(ctx, db.GetOpenQuizSessionParams{
+		UserID:   userID,
+		Language: langSlug,
+		LessonID: lesson.ID,
+	})
+	if err != nil {
+		return quizResult{}, err
+	}
+	if staleSession(langSlug, session, lesson) {
+		return quizResult{}, errQuizChanged
+	}
 
-		case "type_answer":
-			answer = strings.TrimSpace(answer)
-			for _, ca := range q.CorrectAnswers {
-				if strings.EqualFold(answer, ca) {
-					correct++
-					break
-				}
-			}
+	res := gradeQuiz(sessionQuiz(langSlug, lesson, session), answerFor)
+	res.UserID = userID
+	res.Language = langSlug
+	if err := recordSessionResult(ctx, qtx, session, &res); err != nil {
+		return quizResult{}, err
+	}
+	return res, tx.Commit()
+}
+
+// newQuizSeed picks the seed an attempt's generated or sampled questions
+// are chosen by, or 0 for a lesson whose quiz is always the same. Seeds are
+// stored in the quiz_sessions table, so they are kept to non-negative int64s.
+func newQuizSeed(lesson *lessons.Lesson) int64 {
+	if !lesson.Randomized() {
+		return 0
+	}
+	return rand.Int64()
+}
+
+// renderResults shows the results page for a graded and saved quiz.
+func (h *QuizHandler) renderResults(w http.ResponseWriter, r *http.Request, lesson *lessons.Lesson, langConfig *lessons.Language, res quizResult) {
+	nextLessonID := ""
+	if res.Passed {
+		nextLessonID = lessons.GetNextLessonID(res.Language, res.LessonID)
+	}
+
+	h.tmpl.Render(w, "results.html", map[string]interface{}{
+		"Title":          "Quiz Results",
+		"Lesson":         lesson,
+		"Score":          res.Score,
+		"Correct":        res.Correct,
+		"Total":          res.Total,
+		"Earned":         res.Earned,
+		"Possible":       res.Possible,
+		"Weighted":       isWeighted(res),
+		"Questions":      res.Questions,
+		"PassScore":      res.PassScore,
+		"Passed":         res.Passed,
+		"Badges":         newBadgeViews(res.Badges),
+		"Perfect":        res.Score >= 100,
+		"Excellent":      res.Score >= 90,
+		"HalfWay":        res.Score >= 50,
+		"NextLessonID":   nextLessonID,
+		"User":           getUser(r.Context(), h.queries, res.UserID),
+		"LanguageSlug":   res.Language,
+		"LanguageName":   langConfig.DisplayName,
+		"LanguageConfig": langConfig,
+	})
+}
 
-		case "match_pairs":
-			if isMatchCorrect(answer, q.Pairs, q.ShuffledTarget) {
-				correct++
+// questionVocab maps a graded question back to the vocab words it tested.
+// Match pairs update each pair's word individually; other questions update
+// their tracked word, if any.
+func questionVocab(q lessons.Question, answer string, isCorrect bool) []vocabResult {
+	if q.Type == "match_pairs" {
+		var vocab []vocabResult
+		right := matchedPairs(answer, q.Pairs, q.ShuffledTarget)
+		for j, p := range q.Pairs {
+			if p.TrackedWordID != "" {
+				vocab = append(vocab, vocabResult{WordID: p.TrackedWordID, Correct: right[j]})
 			}
 		}
+		return vocab
 	}
+	if q.TrackedWordID == "" {
+		return nil
+	}
+	return []vocabResult{{WordID: q.TrackedWordID, Correct: isCorrect}}
+}
 
-	score := 0
-	if total > 0 {
-		score = correct * 100 / total
+// isWeighted reports whether the results need a points breakdown: some
+// question carries a non-default weight or earned partial credit.
+func isWeighted(res quizResult) bool {
+	for _, q := range res.Questions {
+		if q.Weight != 1 || (q.Credit > 0 && q.Credit < 1) {
+			return true
+		}
+	}
+	return false
+}
+
+// gradeQuiz grades every question of a lesson's quiz, reading each answer
+// from answerFor; a missing answer earns nothing. Each question earns its
+// weight times the fraction it got right; the score is the weighted
+// percentage, rounded to a whole number. The caller fills in UserID and
+// Language.
+func gradeQuiz(lesson *lessons.Lesson, answerFor func(i int) string) quizResult {
+	res := quizResult{LessonID: lesson.ID, Version: lesson.Version, Total: len(lesson.Quiz.Questions), PassScore: lesson.PassScore}
+	for i, q := range lesson.Quiz.Questions {
+		answer := answerFor(i)
+		credit := questionCredit(q, answer)
+		isCorrect := credit >= 1
+		if isCorrect {
+			res.Correct++
+		}
+		res.Earned += credit * q.Points()
+		res.Possible += q.Points()
+		res.Questions = append(res.Questions, questionResult{
+			Number: i + 1,
+			Type:   q.Type,
+			Weight: q.Points(),
+			Credit: credit,
+			Points: credit * q.Points(),
+			Answer: answer,
+		})
+
+		res.Vocab = append(res.Vocab, questionVocab(q, answer, isCorrect)...)
+	}
+
+	if res.Possible > 0 {
+		res.Score = int(math.Round(res.Earned * 100 / res.Possible))
+	}
+	res.Passed = res.Score >= res.PassScore
+	return res
+}
+
+// questionCredit returns the fraction of q answered correctly, from 0 to 1.
+// Answers use the same encoding as the quiz form: an option index, typed text,
+// or match-pair JSON. Only match_pairs can earn partial credit.
+func questionCredit(q lessons.Question, answer string) float64 {
+	switch q.Type {
+	case "multiple_choice", "listen_and_choose":
+		idx, err := strconv.Atoi(answer)
+		if err == nil && idx == q.Correct {
+			return 1
+		}
+
+	case "type_answer":
+		answer = strings.TrimSpace(answer)
+		for _, ca := range q.CorrectAnswers {
+			if strings.EqualFold(answer, ca) {
+				return 1
+			}
+		}
+
+	case "match_pairs":
+		return matchCredit(answer, q.Pairs, q.ShuffledTarget)
 	}
+	return 0
+}
+
+// gradeQuestion reports whether answer is fully correct for q.
+func gradeQuestion(q lessons.Question, answer string) bool {
+	return questionCredit(q, answer) >= 1
+}
 
-	// Save quiz attempt
+// recordQuizResult writes the attempt, vocab mastery, next-lesson unlock and
+// lesson progress using q, which the caller must bind to a transaction so a
+// submission is all or nothing. Newly earned badges are added to res.
+func recordQuizResult(ctx context.Context, q *db.Queries, res *quizResult) (db.QuizAttempt, error) {
 	now := sql.NullTime{Time: time.Now(), Valid: true}
-	h.queries.CreateQuizAttempt(r.Context(), db.CreateQuizAttemptParams{
-		UserID:         userID,
-		Language:       langSlug,
-		LessonID:       lessonID,
-		Score:          int64(score),
-		TotalQuestions: int64(total),
-		CorrectAnswers: int64(correct),
+
+	attempt, err := q.CreateQuizAttempt(ctx, db.CreateQuizAttemptParams{
+		UserID:         res.UserID,
+		Language:       res.Language,
+		LessonID:       res.LessonID,
+		Score:          int64(res.Score),
+		TotalQuestions: int64(res.Total),
+		CorrectAnswers: int64(res.Correct),
+		LessonVersion:  res.Version,
 	})
+	if err != nil {
+		return db.QuizAttempt{}, fmt.Errorf("create quiz attempt: %w", err)
+	}
+
+	for _, qr := range res.Questions {
+		if err := q.SaveQuestionResult(ctx, db.SaveQuestionResultParams{
+			AttemptID:     attempt.ID,
+			QuestionIndex: int64(qr.Number - 1),
+			Credit:        qr.Credit,
+			Correct:       qr.Credit >= 1,
+			Answer:        qr.Answer,
+		}); err != nil {
+			return db.QuizAttempt{}, fmt.Errorf("save question %d result: %w", qr.Number, err)
+		}
+	}
+
+	for _, v := range res.Vocab {
+		if err := updateVocabCorrect(ctx, q, res.UserID, res.Language, v.WordID, v.Correct); err != nil {
+			return db.QuizAttempt{}, fmt.Errorf("update vocab %q: %w", v.WordID, err)
+		}
+	}
+
+	lesson := lessons.GetLesson(res.Language, res.LessonID)
+	current, err := lessonStatus(ctx, q, res.UserID, res.Language, lesson)
+	if err != nil {
+		return db.QuizAttempt{}, fmt.Errorf("read lesson status: %w", err)
+	}
+	event := lessons.EventQuizFailed
+	if res.Passed {
+		event = lessons.EventQuizPassed
+	}
+	status := current.Next(event)
 
-	// Update lesson progress
-	status := "in_progress"
 	var completedAt sql.NullTime
-	if score >= 70 {
-		status = "completed"
+	if status == lessons.StatusCompleted {
 		completedAt = now
+	}
 
-		// Unlock next lesson
-		nextID := lessons.GetNextLessonID(langSlug, lessonID)
-		if nextID != "" {
-			progress, err := h.queries.GetLessonProgress(r.Context(), db.GetLessonProgressParams{
-				UserID:   userID,
-				Language: langSlug,
-				LessonID: nextID,
-			})
-			if err != nil || progress.Status == "locked" {
-				h.queries.UpsertLessonProgress(r.Context(), db.UpsertLessonProgressParams{
-					UserID:   userID,
-					Language: langSlug,
-					LessonID: nextID,
-					Status:   "available",
-				})
+	if res.Passed {
+		if nextID := lessons.GetNextLessonID(res.Language, res.LessonID); nextID != "" {
+			if err := q.UnlockLesson(ctx, db.UnlockLessonParams{
+				UserID:     res.UserID,
+				Language:   res.Language,
+				LessonID:   nextID,
+				StatusRank: int64(lessons.StatusAvailable.Rank()),
+			}); err != nil {
+				return db.QuizAttempt{}, fmt.Errorf("unlock lesson %q: %w", nextID, err)
 			}
 		}
 	}
 
-	// Get current attempts count
-	existing, _ := h.queries.GetLessonProgress(r.Context(), db.GetLessonProgressParams{
-		UserID:   userID,
-		Language: langSlug,
-		LessonID: lessonID,
-	})
-	attempts := int64(1)
-	if existing.Attempts.Valid {
-		attempts = existing.Attempts.Int64 + 1
-	}
-
-	h.queries.UpsertLessonProgress(r.Context(), db.UpsertLessonProgressParams{
-		UserID:       userID,
-		Language:     langSlug,
-		LessonID:     lessonID,
-		Status:       status,
-		BestScore:    sql.NullInt64{Int64: int64(score), Valid: true},
-		Attempts:     sql.NullInt64{Int64: attempts, Valid: true},
+	if _, err := q.RecordLessonAttempt(ctx, db.RecordLessonAttemptParams{
+		UserID:       res.UserID,
+		Language:     res.Language,
+		LessonID:     res.LessonID,
+		Status:       string(status),
+		StatusRank:   int64(status.Rank()),
+		BestScore:    sql.NullInt64{Int64: int64(res.Score), Valid: true},
 		LastAccessed: now,
 		CompletedAt:  completedAt,
-	})
+		Passed:       res.Passed,
+	}); err != nil {
+		return db.QuizAttempt{}, fmt.Errorf("record lesson attempt: %w", err)
+	}
 
-	nextLessonID := ""
-	if score >= 70 {
-		nextLessonID = lessons.GetNextLessonID(langSlug, lessonID)
+	reviews := int64(len(res.Vocab))
+	if err := recordActivity(ctx, q, res.UserID, db.AddDailyActivityParams{
+		Xp:      activity.QuizXP(res.Score) + reviews*activity.ReviewXP,
+		Quizzes: 1,
+		Reviews: reviews,
+	}); err != nil {
+		return db.QuizAttempt{}, fmt.Errorf("record daily activity: %w", err)
 	}
 
-	h.tmpl.Render(w, "results.html", map[string]interface{}{
-		"Title":          "Quiz Results",
-		"Lesson":         lesson,
-		"Score":          score,
-		"Correct":        correct,
-		"Total":          total,
-		"Passed":         score >= 70,
-		"Perfect":        score >= 100,
-		"Excellent":      score >= 90,
-		"HalfWay":        score >= 50,
-		"NextLessonID":   nextLessonID,
-		"User":           getUser(r.Context(), h.queries, userID),
-		"LanguageSlug":   langSlug,
-		"LanguageName":   langConfig.DisplayName,
-		"LanguageConfig": langConfig,
-	})
+	res.Badges, err = awardAchievements(ctx, q, res.UserID)
+	if err != nil {
+		return db.QuizAttempt{}, fmt.Errorf("award achievements: %w", err)
+	}
+
+	return attempt, nil
+}
+
+// matchCredit returns the fraction of pairs matched correctly.
+func matchCredit(answer string, pairs []lessons.Pair, shuffled []string) float64 {
+	if len(pairs) == 0 {
+		return 0
+	}
+	right := 0
+	for _, ok := range matchedPairs(answer, pairs, shuffled) {
+		if ok {
+			right++
+		}
+	}
+	return float64(right) / float64(len(pairs))
 }
 
-func isMatchCorrect(answer string, pairs []lessons.Pair, shuffled []string) bool {
+// matchedPairs reports, for each pair, whether the answer matched it correctly.
+func matchedPairs(answer string, pairs []lessons.Pair, shuffled []string) []bool {
+	right := make([]bool, len(pairs))
 	if answer == "" {
-		return false
+		return right
 	}
 
 	var matched []struct {
@@ -211,7 +462,7 @@ func isMatchCorrect(answer string, pairs []lessons.Pair, shuffled []string) bool
 			Serbian int `json:"serbian"`
 		}
 		if err2 := json.Unmarshal([]byte(answer), &legacy); err2 != nil {
-			return false
+			return right
 		}
 		for _, m := range legacy {
 			matched = append(matched, struct {
@@ -221,35 +472,38 @@ func isMatchCorrect(answer string, pairs []lessons.Pair, shuffled []string) bool
 		}
 	}
 
-	if len(matched) != len(pairs) {
-		return false
+	// A matching is one to one: more entries than pairs earns nothing, and
+	// only the first entry for an English word or target word counts, so
+	// submitting every combination can't credit every pair
+	if len(matched) > len(pairs) {
+		return right
 	}
-
+	seenEnglish := make(map[int]bool)
+	usedTarget := make(map[int]bool)
 	for _, m := range matched {
 		if m.English < 0 || m.English >= len(pairs) || m.Target < 0 || m.Target >= len(shuffled) {
-			return false
+			continue
+		}
+		if seenEnglish[m.English] || usedTarget[m.Target] {
+			continue
 		}
-		expected := pairs[m.English].Target
-		actual := shuffled[m.Target]
-		if expected != actual {
-			return false
+		seenEnglish[m.English], usedTarget[m.Target] = true, true
+		if pairs[m.English].Target == shuffled[m.Target] {
+			right[m.English] = true
 		}
 	}
-
-	return true
+	return right
 }
 
-func updateVocabCorrect(r *http.Request, q *db.Queries, userID int64, langSlug string, question lessons.Question, isCorrect bool) {
-	wordID := question.WordID
-	if wordID == "" {
-		return
-	}
-
-	existing, err := q.GetVocabProgressByWord(r.Context(), db.GetVocabProgressByWordParams{
+func updateVocabCorrect(ctx context.Context, q *db.Queries, userID int64, langSlug, wordID string, isCorrect bool) error {
+	existing, err := q.GetVocabProgressByWord(ctx, db.GetVocabProgressByWordParams{
 		UserID:   userID,
 		Language: langSlug,
 		WordID:   wordID,
 	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
 
 	timesCorrect := int64(0)
 	timesIncorrect := int64(0)
@@ -285,7 +539,7 @@ func updateVocabCorrect(r *http.Request, q *db.Queries, userID int64, langSlug s
 		}
 	}
 
-	q.UpsertVocabProgress(r.Context(), db.UpsertVocabProgressParams{
+	_, err = q.UpsertVocabProgress(ctx, db.UpsertVocabProgressParams{
 		UserID:         userID,
 		Language:       langSlug,
 		WordID:         wordID,
@@ -294,4 +548,5 @@ func updateVocabCorrect(r *http.Request, q *db.Queries, userID int64, langSlug s
 		MasteryLevel:   sql.NullInt64{Int64: mastery, Valid: true},
 		LastReviewed:   sql.NullTime{Time: time.Now(), Valid: true},
 	})
+	return err
 }
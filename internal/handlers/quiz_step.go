@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"speakeasy/internal/db"
+	"speakeasy/internal/lessons"
+	"speakeasy/internal/middleware"
+)
+
+// StepPage serves the one-question-at-a-time quiz. A normal page load renders
+// the layout around the current question; htmx requests get only the partial.
+// Answers live in a quiz session, so a refresh resumes where the user left off.
+func (h *QuizHandler) StepPage(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	langConfig, lesson, ok := h.quizLesson(w, r, userID)
+	if !ok {
+		return
+	}
+
+	session, restarted, err := h.openQuizSession(r.Context(), userID, langConfig.Slug, lesson)
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't start your quiz. Please try again.")
+		return
+	}
+	answers, err := h.sessionAnswers(r.Context(), session.ID)
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't load your quiz answers.")
+		return
+	}
+
+	attempt := sessionQuiz(langConfig.Slug, lesson, session)
+	step := stepData(langConfig, attempt, answers)
+	step["Restarted"] = restarted
+	next := nextUnanswered(attempt, answers)
+	if next >= 0 {
+		step["Index"] = next
+		step["Question"] = attempt.Quiz.Questions[next]
+	}
+
+	if isHTMX(r) {
+		if next < 0 {
+			h.tmpl.RenderPartial(w, "quiz-step-finish", step)
+		} else {
+			h.tmpl.RenderPartial(w, "quiz-step-question", step)
+		}
+		return
+	}
+
+	h.tmpl.Render(w, "quiz_step.html", map[string]interface{}{
+		"Title":          "Quiz: " + lesson.Title,
+		"Lesson":         lesson,
+		"Step":           step,
+		"User":           getUser(r.Context(), h.queries, userID),
+		"LanguageSlug":   langConfig.Slug,
+		"LanguageName":   langConfig.DisplayName,
+		"LanguageConfig": langConfig,
+	})
+}
+
+// AnswerStep grades and stores one answer, then returns the feedback partial.
+// Only the first answer to a question counts; resubmitting shows the stored one.
+func (h *QuizHandler) AnswerStep(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	langConfig, lesson, ok := h.quizLesson(w, r, userID)
+	if !ok {
+		return
+	}
+	stepURL := "/lessons/" + langConfig.Slug + "/" + lesson.ID + "/quiz/step"
+	if !isHTMX(r) {
+		http.Redirect(w, r, stepURL, http.StatusSeeOther)
+		return
+	}
+
+	session, restarted, err := h.openQuizSession(r.Context(), userID, langConfig.Slug, lesson)
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't save your answer. Please try again.")
+		return
+	}
+	attempt := sessionQuiz(langConfig.Slug, lesson, session)
+	if restarted {
+		// The answer was to a question of the old version; start over
+		step := stepData(langConfig, attempt, nil)
+		step["Restarted"] = true
+		if len(attempt.Quiz.Questions) == 0 {
+			h.tmpl.RenderPartial(w, "quiz-step-finish", step)
+			return
+		}
+		step["Index"] = 0
+		step["Question"] = attempt.Quiz.Questions[0]
+		h.tmpl.RenderPartial(w, "quiz-step-question", step)
+		return
+	}
+
+	idx, err := strconv.Atoi(r.FormValue("index"))
+	if err != nil || idx < 0 || idx >= len(attempt.Quiz.Questions) {
+		http.Error(w, "invalid question index", http.StatusBadRequest)
+		return
+	}
+	q := attempt.Quiz.Questions[idx]
+	answer := r.FormValue("answer-" + strconv.Itoa(idx))
+	if err := h.queries.SaveQuizAnswer(r.Context(), db.SaveQuizAnswerParams{
+		SessionID:     session.ID,
+		QuestionIndex: int64(idx),
+		Answer:        answer,
+		Correct:       gradeQuestion(q, answer),
+	}); err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't save your answer. Please try again.")
+		return
+	}
+
+	answers, err := h.sessionAnswers(r.Context(), session.ID)
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't load your quiz answers.")
+		return
+	}
+
+	step := stepData(langConfig, attempt, answers)
+	step["Index"] = idx
+	step["Question"] = q
+	step["Correct"] = answers[idx].Correct
+	step["Credit"] = questionCredit(q, answers[idx].Answer)
+	step["Finished"] = nextUnanswered(attempt, answers) < 0
+	switch q.Type {
+	case "match_pairs":
+		step["CorrectPairs"] = q.Pairs
+	default:
+		step["CorrectAnswer"] = correctAnswerText(q)
+		step["AudioText"] = feedbackAudio(q)
+	}
+
+	h.tmpl.RenderPartial(w, "quiz-step-feedback", step)
+}
+
+// FinishStep scores the open quiz session from its stored answers, records the
+// result exactly like a full-form submission and closes the session.
+func (h *QuizHandler) FinishStep(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	langConfig, lesson, ok := h.quizLesson(w, r, userID)
+	if !ok {
+		return
+	}
+
+	res, err := h.finishQuizSession(r.Context(), userID, langConfig.Slug, lesson)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Already finished, e.g. a double-clicked "See Results"
+		http.Redirect(w, r, "/lessons/"+langConfig.Slug+"/"+lesson.ID, http.StatusSeeOther)
+		return
+	}
+	if errors.Is(err, errQuizChanged) || errors.Is(err, errQuizUnanswered) {
+		// The step page starts the quiz again and says why, or shows the
+		// first question still to answer
+		http.Redirect(w, r, "/lessons/"+langConfig.Slug+"/"+lesson.ID+"/quiz/step", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't save your quiz result. Please try again.")
+		return
+	}
+
+	h.renderResults(w, r, lesson, langConfig, res)
+}
+
+func (h *QuizHandler) finishQuizSession(ctx context.Context, userID int64, langSlug string, lesson *lessons.Lesson) (quizResult, error) {
+	tx, err := h.database.BeginTx(ctx, nil)
+	if err != nil {
+		return quizResult{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+
+	session, err := qtx.GetOpenQuizSession(ctx, db.GetOpenQuizSessionParams{
+		UserID:   userID,
+		Language: langSlug,
+		LessonID: lesson.ID,
+	})
+	if err != nil {
+		return quizResult{}, err
+	}
+	if staleSession(langSlug, session, lesson) {
+		return quizResult{}, errQuizChanged
+	}
+	rows, err := qtx.ListQuizAnswers(ctx, session.ID)
+	if err != nil {
+		return quizResult{}, fmt.Errorf("list quiz answers: %w", err)
+	}
+	answers := make(map[int]string, len(rows))
+	for _, a := range rows {
+		answers[int(a.QuestionIndex)] = a.Answer
+	}
+
+	quiz := sessionQuiz(langSlug, lesson, session)
+	for i := range quiz.Quiz.Questions {
+		if _, ok := answers[i]; !ok {
+			return quizResult{}, errQuizUnanswered
+		}
+	}
+	res := gradeQuiz(quiz, func(i int) string {
+		return answers[i]
+	})
+	res.UserID = userID
+	res.Language = langSlug
+
+	if err := recordSessionResult(ctx, qtx, session, &res); err != nil {
+		return quizResult{}, err
+	}
+	return res, tx.Commit()
+}
+
+// recordSessionResult records a graded quiz session like recordQuizResult
+// and closes the session against the attempt. q must be bound to a
+// transaction.
+func recordSessionResult(ctx context.Context, q *db.Queries, session db.QuizSession, res *quizResult) error {
+	attempt, err := recordQuizResult(ctx, q, res)
+	if err != nil {
+		return err
+	}
+	if _, err := q.FinishQuizSession(ctx, db.FinishQuizSessionParams{
+		FinishedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		AttemptID:  sql.NullInt64{Int64: attempt.ID, Valid: true},
+		ID:         session.ID,
+	}); err != nil {
+		return fmt.Errorf("finish quiz session: %w", err)
+	}
+	return nil
+}
+
+// errQuizChanged means the lesson was edited since its open quiz session
+// started, so the stored answers are to different questions.
+var errQuizChanged = errors.New("quiz changed since the session started")
+
+// errQuizUnanswered means a quiz session was finished before every question
+// had an answer.
+var errQuizUnanswered = errors.New("quiz has unanswered questions")
+
+// openQuizSession returns the user's unfinished session for a lesson quiz,
+// starting a new one, with a new seed for any generated questions, if there
+// is none. A session started on an earlier version of the lesson is closed
+// unscored and replaced; restarted reports that it was. The database allows
+// one open session per lesson, so concurrent requests share the same one.
+func (h *QuizHandler) openQuizSession(ctx context.Context, userID int64, langSlug string, lesson *lessons.Lesson) (session db.QuizSession, restarted bool, err error) {
+	session, err = h.queries.GetOpenQuizSession(ctx, db.GetOpenQuizSessionParams{
+		UserID:   userID,
+		Language: langSlug,
+		LessonID: lesson.ID,
+	})
+	if err == nil && staleSession(langSlug, session, lesson) {
+		if _, err := h.queries.FinishQuizSession(ctx, db.FinishQuizSessionParams{
+			FinishedAt: sql.NullTime{Time: time.Now(), Valid: true},
+			ID:         session.ID,
+		}); err != nil {
+			return db.QuizSession{}, false, fmt.Errorf("close outdated quiz session: %w", err)
+		}
+		restarted, err = true, sql.ErrNoRows
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		session, err = h.queries.CreateQuizSession(ctx, db.CreateQuizSessionParams{
+			UserID:        userID,
+			Language:      langSlug,
+			LessonID:      lesson.ID,
+			LessonVersion: lessons.QuizVersion(langSlug, lesson),
+			QuizSeed:      newQuizSeed(lesson),
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			// Another request, say from a second tab, opened one first
+			session, err = h.queries.GetOpenQuizSession(ctx, db.GetOpenQuizSessionParams{
+				UserID:   userID,
+				Language: langSlug,
+				LessonID: lesson.ID,
+			})
+		}
+	}
+	return session, restarted, err
+}
+
+// sessionQuiz returns the lesson as quizzed in session, with the generated
+// questions its seed gives, if the lesson has any.
+func sessionQuiz(langSlug string, lesson *lessons.Lesson, session db.QuizSession) *lessons.Lesson {
+	return lessons.ForAttempt(langSlug, lesson, uint64(session.QuizSeed))
+}
+
+// staleSession reports whether a quiz session was started on an earlier
+// version of the lesson's quiz (see lessons.QuizVersion). Sessions from
+// before versions were recorded are taken to be current.
+func staleSession(langSlug string, session db.QuizSession, lesson *lessons.Lesson) bool {
+	return session.LessonVersion != "" && session.LessonVersion != lessons.QuizVersion(langSlug, lesson)
+}
+
+// sessionAnswers returns a session's stored answers keyed by question index.
+func (h *QuizHandler) sessionAnswers(ctx context.Context, sessionID int64) (map[int]db.QuizAnswer, error) {
+	rows, err := h.queries.ListQuizAnswers(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	answers := make(map[int]db.QuizAnswer, len(rows))
+	for _, a := range rows {
+		answers[int(a.QuestionIndex)] = a
+	}
+	return answers, nil
+}
+
+// stepData holds the values every quiz-step partial needs.
+func stepData(langConfig *lessons.Language, lesson *lessons.Lesson, answers map[int]db.QuizAnswer) map[string]interface{} {
+	total := len(lesson.Quiz.Questions)
+	percent := 0
+	if total > 0 {
+		percent = len(answers) * 100 / total
+	}
+	base := "/lessons/" + langConfig.Slug + "/" + lesson.ID + "/quiz"
+	return map[string]interface{}{
+		"Answered":       len(answers),
+		"Total":          total,
+		"Percent":        percent,
+		"Finished":       nextUnanswered(lesson, answers) < 0,
+		"StepURL":        base + "/step",
+		"FinishURL":      base + "/finish",
+		"LanguageName":   langConfig.DisplayName,
+		"LanguageConfig": langConfig,
+	}
+}
+
+// nextUnanswered returns the index of the first question without a stored
+// answer, or -1 when every question has been answered.
+func nextUnanswered(lesson *lessons.Lesson, answers map[int]db.QuizAnswer) int {
+	for i := range lesson.Quiz.Questions {
+		if _, ok := answers[i]; !ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// correctAnswerText is the answer shown to the user after a wrong guess.
+func correctAnswerText(q lessons.Question) string {
+	switch q.Type {
+	case "multiple_choice", "listen_and_choose":
+		if q.Correct >= 0 && q.Correct < len(q.Options) {
+			return q.Options[q.Correct]
+		}
+	case "type_answer":
+		if len(q.CorrectAnswers) > 0 {
+			return q.CorrectAnswers[0]
+		}
+	}
+	return ""
+}
+
+// feedbackAudio is the target-language text to play alongside the feedback,
+// or "" when the question has no target-language answer to speak.
+func feedbackAudio(q lessons.Question) string {
+	switch q.Type {
+	case "listen_and_choose":
+		return q.AudioText
+	case "type_answer":
+		return correctAnswerText(q)
+	}
+	return ""
+}
+
+// isHTMX reports whether the request was made by htmx rather than a full
+// page navigation.
+func isHTMX(r *http.Request) bool {
+	return r.Header.Get("HX-Request") == "true"
+}
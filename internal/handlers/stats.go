@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"speakeasy/internal/activity"
+	"speakeasy/internal/db"
+	"speakeasy/internal/lessons"
+	"speakeasy/internal/middleware"
+)
+
+// hardestWordsShown is how many words the error-rate chart lists, and
+// hardestWordsMinTries how often a word must have been tested to qualify.
+const (
+	hardestWordsShown    = 10
+	hardestWordsMinTries = 2
+)
+
+type StatsHandler struct {
+	queries *db.Queries
+	tmpl    *TemplateRenderer
+}
+
+func NewStatsHandler(q *db.Queries, t *TemplateRenderer) *StatsHandler {
+	return &StatsHandler{queries: q, tmpl: t}
+}
+
+// LessonStats is one lesson's score history on the stats page.
+type LessonStats struct {
+	ID             string
+	Title          string
+	Order          int
+	Attempts       int
+	Best           int
+	PassScore      int
+	AttemptsToPass int // 0 until the quiz has been passed
+	Chart          LineChart
+}
+
+// Index sends /stats to the first language's stats.
+func (h *StatsHandler) Index(w http.ResponseWriter, r *http.Request) {
+	langs := lessons.GetLanguages()
+	if len(langs) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	http.Redirect(w, r, "/stats/"+langs[0].Slug, http.StatusSeeOther)
+}
+
+// Page renders /stats/{language}: score history per lesson, attempts needed
+// to pass, word mastery, the hardest words and when the user studies.
+func (h *StatsHandler) Page(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	langConfig := lessons.GetLanguage(extractLanguage(r.URL.Path))
+	if langConfig == nil {
+		http.NotFound(w, r)
+		return
+	}
+	user, err := h.queries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't load your account.")
+		return
+	}
+	loc := activity.Location(user.Timezone)
+
+	// Score history and attempts-to-pass, from every quiz attempt per lesson
+	var lessonStats []LessonStats
+	var hourCounts [24]float64
+	var passLabels, passTitles []string
+	var passValues []float64
+	for _, l := range lessons.GetLessons(langConfig.Slug, user.IsAdmin) {
+		attempts, err := h.queries.ListQuizAttempts(r.Context(), db.ListQuizAttemptsParams{
+			UserID:   userID,
+			Language: langConfig.Slug,
+			LessonID: l.ID,
+		})
+		if err != nil {
+			serverError(w, r, h.tmpl, err, "We couldn't load your quiz history.")
+			return
+		}
+		// Oldest first
+		sort.Slice(attempts, func(i, j int) bool {
+			return attempts[i].AttemptedAt.Time.Before(attempts[j].AttemptedAt.Time)
+		})
+
+		ls := LessonStats{ID: l.ID, Title: l.Title, Order: l.Order, Attempts: len(attempts), PassScore: l.PassScore}
+		var scores []int
+		var titles []string
+		for i, a := range attempts {
+			score := int(a.Score)
+			scores = append(scores, score)
+			at := a.AttemptedAt.Time.In(loc)
+			titles = append(titles, at.Format("2 Jan 2006 15:04")+": "+strconv.Itoa(score)+"%")
+			if score > ls.Best {
+				ls.Best = score
+			}
+			if ls.AttemptsToPass == 0 && score >= l.PassScore {
+				ls.AttemptsToPass = i + 1
+			}
+			if a.AttemptedAt.Valid {
+				hourCounts[at.Hour()]++
+			}
+		}
+		ls.Chart = scoreLine(scores, titles, l.PassScore, 260, 90)
+		lessonStats = append(lessonStats, ls)
+
+		if ls.AttemptsToPass > 0 {
+			passLabels = append(passLabels, strconv.Itoa(l.Order)+". "+l.Title)
+			passValues = append(passValues, float64(ls.AttemptsToPass))
+			passTitles = append(passTitles, l.Title+": passed on attempt "+strconv.Itoa(ls.AttemptsToPass))
+		}
+	}
+
+	// Mastery distribution and hardest words, from vocab progress
+	rows, err := loadWordRows(r.Context(), h.queries, userID, langConfig.Slug)
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't load your word progress.")
+		return
+	}
+	masteryLabels := []string{"New", "0", "1", "2", "3", "4", "5"}
+	masteryValues := make([]float64, len(masteryLabels))
+	var hardest []WordRow
+	for _, row := range rows {
+		if !row.Seen {
+			masteryValues[0]++
+			continue
+		}
+		if row.Mastery >= 0 && row.Mastery <= 5 {
+			masteryValues[row.Mastery+1]++
+		}
+		if row.TimesCorrect+row.TimesIncorrect >= hardestWordsMinTries && row.TimesIncorrect > 0 {
+			hardest = append(hardest, row)
+		}
+	}
+	masteryTitles := make([]string, len(masteryLabels))
+	for i, v := range masteryValues {
+		label := "Mastery " + masteryLabels[i]
+		if i == 0 {
+			label = "Not seen yet"
+		}
+		masteryTitles[i] = label + ": " + strconv.Itoa(int(v)) + " words"
+	}
+
+	sort.SliceStable(hardest, func(i, j int) bool {
+		return errorRate(hardest[i]) > errorRate(hardest[j])
+	})
+	if len(hardest) > hardestWordsShown {
+		hardest = hardest[:hardestWordsShown]
+	}
+	var hardLabels, hardTitles []string
+	var hardValues []float64
+	for _, row := range hardest {
+		hardLabels = append(hardLabels, row.TargetPrimary)
+		hardValues = append(hardValues, errorRate(row)*100)
+		hardTitles = append(hardTitles, row.TargetPrimary+" ("+row.English+"): wrong "+
+			strconv.FormatInt(row.TimesIncorrect, 10)+" of "+
+			strconv.FormatInt(row.TimesCorrect+row.TimesIncorrect, 10))
+	}
+
+	hourLabels := make([]string, 24)
+	hourTitles := make([]string, 24)
+	for hr := range hourLabels {
+		if hr%3 == 0 {
+			hourLabels[hr] = strconv.Itoa(hr)
+		}
+		hourTitles[hr] = strconv.Itoa(hr) + ":00–" + strconv.Itoa(hr) + ":59: " + strconv.Itoa(int(hourCounts[hr])) + " quizzes"
+	}
+
+	count := func(f float64) string { return strconv.Itoa(int(f)) }
+	percent := func(f float64) string { return strconv.Itoa(int(f+0.5)) + "%" }
+	hourValue := func(float64) string { return "" }
+
+	h.tmpl.Render(w, "stats.html", map[string]interface{}{
+		"Title":          langConfig.DisplayName + " Statistics",
+		"User":           &user,
+		"Languages":      lessons.GetLanguages(),
+		"LessonStats":    lessonStats,
+		"PassChart":      rowChart(passLabels, passValues, count, passTitles, 560, 0),
+		"MasteryChart":   columnChart(masteryLabels, masteryValues, count, masteryTitles, 560, 180),
+		"HardestChart":   rowChart(hardLabels, hardValues, percent, hardTitles, 560, 100),
+		"HourChart":      columnChart(hourLabels, hourCounts[:], hourValue, hourTitles, 560, 140),
+		"Timezone":       loc.String(),
+		"LanguageSlug":   langConfig.Slug,
+		"LanguageName":   langConfig.DisplayName,
+		"LanguageConfig": langConfig,
+	})
+}
+
+func errorRate(row WordRow) float64 {
+	total := row.TimesCorrect + row.TimesIncorrect
+	if total == 0 {
+		return 0
+	}
+	return float64(row.TimesIncorrect) / float64(total)
+}
@@ -40,6 +40,11 @@ func (h *TTSHandler) ServeAudio(w http.ResponseWriter, r *http.Request) {
 	if lang == "" {
 		lang = "sr"
 	}
+	gender, ok := tts.Voice(gender)
+	if !ok {
+		http.Error(w, "unknown voice", http.StatusBadRequest)
+		return
+	}
 
 	data, contentType, err := h.client.GetAudio(text, lang, gender)
 	if err != nil {
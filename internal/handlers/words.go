@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"speakeasy/internal/db"
+	"speakeasy/internal/lessons"
+	"speakeasy/internal/middleware"
+)
+
+// Mastery levels run from 0 to 5 (see updateVocabCorrect). A word the user has
+// seen at or below weakMastery needs practice; masteredLevel and up is known.
+const (
+	weakMastery   = 2
+	masteredLevel = 4
+)
+
+// wordFilters are the notebook filters in the order they are shown.
+var wordFilters = []string{"all", "weak", "unseen", "mastered", "starred"}
+
+type WordsHandler struct {
+	queries *db.Queries
+	tmpl    *TemplateRenderer
+}
+
+func NewWordsHandler(q *db.Queries, t *TemplateRenderer) *WordsHandler {
+	return &WordsHandler{queries: q, tmpl: t}
+}
+
+// WordRow is one word in the notebook together with the user's progress on it.
+type WordRow struct {
+	lessons.Word
+	Seen           bool
+	Mastery        int64
+	TimesCorrect   int64
+	TimesIncorrect int64
+	LastReviewed   time.Time
+	Starred        bool
+}
+
+// Matches reports whether the row passes filter and contains query in its
+// English or target-language text.
+func (w WordRow) Matches(filter, query string) bool {
+	switch filter {
+	case "weak":
+		if !w.Seen || w.Mastery > weakMastery {
+			return false
+		}
+	case "unseen":
+		if w.Seen {
+			return false
+		}
+	case "mastered":
+		if w.Mastery < masteredLevel {
+			return false
+		}
+	case "starred":
+		if !w.Starred {
+			return false
+		}
+	}
+	if query == "" {
+		return true
+	}
+	query = strings.ToLower(query)
+	for _, s := range []string{w.English, w.TargetPrimary, w.TargetAlt} {
+		if strings.Contains(strings.ToLower(s), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// Page lists every word in the language. htmx requests from the search box
+// and filter tabs get only the list partial.
+func (h *WordsHandler) Page(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	langConfig := lessons.GetLanguage(extractLanguage(r.URL.Path))
+	if langConfig == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	rows, err := loadWordRows(r.Context(), h.queries, userID, langConfig.Slug)
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't load your words.")
+		return
+	}
+
+	filter := r.URL.Query().Get("filter")
+	if !validFilter(filter) {
+		filter = "all"
+	}
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	var shown []WordRow
+	counts := make(map[string]int, len(wordFilters))
+	for _, row := range rows {
+		for _, f := range wordFilters {
+			if row.Matches(f, "") {
+				counts[f]++
+			}
+		}
+		if row.Matches(filter, query) {
+			shown = append(shown, row)
+		}
+	}
+
+	data := map[string]interface{}{
+		"Words":          shown,
+		"Filter":         filter,
+		"Filters":        wordFilters,
+		"Counts":         counts,
+		"Query":          query,
+		"BaseURL":        "/lessons/" + langConfig.Slug + "/words",
+		"LanguageSlug":   langConfig.Slug,
+		"LanguageName":   langConfig.DisplayName,
+		"LanguageConfig": langConfig,
+	}
+
+	if isHTMX(r) {
+		h.tmpl.RenderPartial(w, "word-list", data)
+		return
+	}
+
+	data["Title"] = "My " + langConfig.DisplayName + " Words"
+	data["User"] = getUser(r.Context(), h.queries, userID)
+	h.tmpl.Render(w, "words.html", data)
+}
+
+// ToggleStar stars or unstars one word and returns the updated star button.
+func (h *WordsHandler) ToggleStar(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	langConfig := lessons.GetLanguage(extractLanguage(r.URL.Path))
+	if langConfig == nil {
+		http.NotFound(w, r)
+		return
+	}
+	baseURL := "/lessons/" + langConfig.Slug + "/words"
+
+	wordID := r.FormValue("word_id")
+	if !knownWord(langConfig.Slug, wordID) {
+		http.Error(w, "unknown word", http.StatusBadRequest)
+		return
+	}
+
+	starred := r.FormValue("starred") == "true"
+	var err error
+	if starred {
+		err = h.queries.StarWord(r.Context(), db.StarWordParams{
+			UserID:   userID,
+			Language: langConfig.Slug,
+			WordID:   wordID,
+		})
+	} else {
+		err = h.queries.UnstarWord(r.Context(), db.UnstarWordParams{
+			UserID:   userID,
+			Language: langConfig.Slug,
+			WordID:   wordID,
+		})
+	}
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't update your starred words.")
+		return
+	}
+
+	if !isHTMX(r) {
+		http.Redirect(w, r, baseURL, http.StatusSeeOther)
+		return
+	}
+	h.tmpl.RenderPartial(w, "word-star", map[string]interface{}{
+		"ID":      wordID,
+		"Starred": starred,
+		"BaseURL": baseURL,
+	})
+}
+
+// Export downloads the starred words as CSV, or as a tab-separated file that
+// Anki's File → Import reads directly (front: target, back: English).
+func (h *WordsHandler) Export(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	langConfig := lessons.GetLanguage(extractLanguage(r.URL.Path))
+	if langConfig == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	rows, err := loadWordRows(r.Context(), h.queries, userID, langConfig.Slug)
+	if err != nil {
+		serverError(w, r, h.tmpl, err, "We couldn't export your words.")
+		return
+	}
+	var starred []WordRow
+	for _, row := range rows {
+		if row.Starred {
+			starred = append(starred, row)
+		}
+	}
+
+	filename := "speakeasy-" + langConfig.Slug + "-words"
+	switch r.URL.Query().Get("format") {
+	case "anki":
+		w.Header().Set("Content-Type", "text/tab-separated-values; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.txt"`)
+		writeAnkiTSV(w, starred, langConfig)
+	default:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+		writeWordsCSV(w, starred)
+	}
+}
+
+// loadWordRows joins the language's words with the user's vocab progress and stars.
+func loadWordRows(ctx context.Context, q *db.Queries, userID int64, langSlug string) ([]WordRow, error) {
+	progress, err := q.GetVocabProgress(ctx, db.GetVocabProgressParams{
+		UserID:   userID,
+		Language: langSlug,
+	})
+	if err != nil {
+		return nil, err
+	}
+	byWord := make(map[string]db.VocabProgress, len(progress))
+	for _, p := range progress {
+		byWord[p.WordID] = p
+	}
+
+	stars, err := q.ListStarredWords(ctx, db.ListStarredWordsParams{
+		UserID:   userID,
+		Language: langSlug,
+	})
+	if err != nil {
+		return nil, err
+	}
+	starred := make(map[string]bool, len(stars))
+	for _, s := range stars {
+		starred[s.WordID] = true
+	}
+
+	words := lessons.GetWords(langSlug)
+	rows := make([]WordRow, 0, len(words))
+	for _, word := range words {
+		row := WordRow{Word: word, Starred: starred[word.ID]}
+		if p, ok := byWord[word.ID]; ok {
+			row.Seen = true
+			row.Mastery = p.MasteryLevel.Int64
+			row.TimesCorrect = p.TimesCorrect.Int64
+			row.TimesIncorrect = p.TimesIncorrect.Int64
+			row.LastReviewed = p.LastReviewed.Time
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func validFilter(filter string) bool {
+	for _, f := range wordFilters {
+		if f == filter {
+			return true
+		}
+	}
+	return false
+}
+
+func knownWord(langSlug, wordID string) bool {
+	for _, word := range lessons.GetWords(langSlug) {
+		if word.ID == wordID {
+			return true
+		}
+	}
+	return false
+}
+
+func writeWordsCSV(w http.ResponseWriter, rows []WordRow) {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"english", "target", "target_alt", "pronunciation", "lesson", "mastery", "times_correct", "times_incorrect"})
+	for _, row := range rows {
+		cw.Write([]string{
+			csvText(row.English),
+			csvText(row.TargetPrimary),
+			csvText(row.TargetAlt),
+			csvText(row.PronunciationHint),
+			csvText(row.LessonTitle),
+			strconv.FormatInt(row.Mastery, 10),
+			strconv.FormatInt(row.TimesCorrect, 10),
+			strconv.FormatInt(row.TimesIncorrect, 10),
+		})
+	}
+	cw.Flush()
+}
+
+// csvText keeps a spreadsheet from reading a text cell as a formula by
+// prefixing the characters that can start one, tab and carriage return
+// included, with an apostrophe.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// writeAnkiTSV writes Anki's plain-text import format. The header lines tell
+// Anki the separator and columns so no import settings need changing. The
+// file is meant for Anki, which shows fields as text and never evaluates
+// them, so fields are not escaped like csvText's: an apostrophe would show
+// up on the cards.
+func writeAnkiTSV(w http.ResponseWriter, rows []WordRow, lang *lessons.Language) {
+	var b strings.Builder
+	b.WriteString("#separator:tab\n")
+	b.WriteString("#html:false\n")
+	b.WriteString("#columns:Front\tBack\tTags\n")
+	tag := "speakeasy::" + lang.Slug
+	for _, row := range rows {
+		front := row.TargetPrimary
+		if lang.HasDualScript && row.TargetAlt != "" {
+			front += " / " + row.TargetAlt
+		}
+		back := row.English
+		if row.PronunciationHint != "" {
+			back += " (" + row.PronunciationHint + ")"
+		}
+		b.WriteString(ankiField(front) + "\t" + ankiField(back) + "\t" + tag + "\n")
+	}
+	w.Write([]byte(b.String()))
+}
+
+// ankiField strips the characters that would break a TSV row.
+func ankiField(s string) string {
+	return strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,351 @@
+// Package itemstats computes item statistics for quiz questions from every
+// graded answer: how often each question is answered correctly, which options
+// multiple-choice answers pick, and how well a question separates strong
+// attempts from weak ones. Content authors use the report to find confusing
+// or miskeyed questions and fix them in the lesson JSON.
+package itemstats
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"speakeasy/internal/db"
+	"speakeasy/internal/lessons"
+)
+
+const (
+	// MinResponses is how many answers a question needs before its
+	// discrimination is computed and it can be flagged.
+	MinResponses = 10
+
+	// groupShare is the fraction of answers, by attempt score, in each of the
+	// upper and lower groups of the discrimination index.
+	groupShare = 0.27
+
+	hardBelow          = 0.3 // percent correct under which a question is flagged
+	weakDiscrimination = 0.2 // discrimination under which a question is flagged
+)
+
+// Response is one graded answer to a quiz question.
+type Response struct {
+	AttemptID int64
+	Language  string
+	LessonID  string
+	Version   string // the lesson's Version when answered; "" if unknown
+	Question  int    // index in the lesson's quiz
+	Score     int    // the whole attempt's percentage
+	Answer    string // in the quiz form's encoding
+	Correct   bool
+}
+
+// Option is how often a multiple-choice option was picked.
+type Option struct {
+	Letter  string
+	Text    string
+	Correct bool
+	Count   int
+	Percent int // of all answers to the question
+}
+
+// Item is the statistics for one quiz question.
+type Item struct {
+	Language    string
+	LessonID    string
+	LessonTitle string
+	LessonOrder int
+	Number      int // 1-based, as shown in the quiz
+	Type        string
+	Text        string
+	Missing     bool // the lesson or question no longer exists
+
+	Responses      int
+	Correct        int
+	PercentCorrect int
+
+	// Outdated counts answers to an earlier version of the lesson, which
+	// may have asked something else under this number. They are left out
+	// of every other statistic.
+	Outdated int
+
+	// Discrimination is the percent correct in the top 27% of answers by
+	// attempt score minus that in the bottom 27%, from -100 to 100. Good
+	// questions are answered correctly more often by stronger learners.
+	Discrimination    int
+	HasDiscrimination bool
+
+	Options    []Option // multiple_choice and listen_and_choose only
+	Unanswered int      // blank or unreadable answers to those questions
+
+	Flags []string
+}
+
+// Load reads every graded answer from the database.
+func Load(ctx context.Context, q *db.Queries) ([]Response, error) {
+	rows, err := q.ListQuestionResponses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]Response, len(rows))
+	for i, row := range rows {
+		responses[i] = Response{
+			AttemptID: row.AttemptID,
+			Language:  row.Language,
+			LessonID:  row.LessonID,
+			Version:   row.LessonVersion,
+			Question:  int(row.QuestionIndex),
+			Score:     int(row.Score),
+			Answer:    row.Answer,
+			Correct:   row.Correct,
+		}
+	}
+	return responses, nil
+}
+
+// Analyze groups responses by question and computes each question's
+// statistics. Items are ordered by language, lesson and question; questions
+// whose lesson has since been removed come last in their language. Answers
+// to generated and sampled questions are left out, since they asked
+// something different in every attempt.
+func Analyze(responses []Response) []Item {
+	type key struct {
+		language, lessonID string
+		question           int
+	}
+	byItem := make(map[key][]Response)
+	var keys []key
+	for _, r := range responses {
+		if generated(r) {
+			continue
+		}
+		k := key{r.Language, r.LessonID, r.Question}
+		if _, ok := byItem[k]; !ok {
+			keys = append(keys, k)
+		}
+		byItem[k] = append(byItem[k], r)
+	}
+
+	items := make([]Item, len(keys))
+	for i, k := range keys {
+		items[i] = analyzeItem(k.language, k.lessonID, k.question, byItem[k])
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.Language != b.Language {
+			return a.Language < b.Language
+		}
+		if a.Missing != b.Missing {
+			return !a.Missing
+		}
+		if a.LessonOrder != b.LessonOrder {
+			return a.LessonOrder < b.LessonOrder
+		}
+		if a.LessonID != b.LessonID {
+			return a.LessonID < b.LessonID
+		}
+		return a.Number < b.Number
+	})
+	return items
+}
+
+// generated reports whether r answers one of the questions its lesson
+// generates or samples after the hand-written ones.
+func generated(r Response) bool {
+	lesson := lessons.GetLesson(r.Language, r.LessonID)
+	return lesson != nil && lesson.Randomized() && r.Question >= len(lesson.Quiz.Questions)
+}
+
+func analyzeItem(language, lessonID string, index int, responses []Response) Item {
+	item := Item{
+		Language:    language,
+		LessonID:    lessonID,
+		LessonTitle: lessonID,
+		Number:      index + 1,
+	}
+
+	lesson := lessons.GetLesson(language, lessonID)
+	if lesson != nil {
+		var current []Response
+		for _, r := range responses {
+			if r.Version != "" && r.Version != lesson.Version {
+				item.Outdated++
+				continue
+			}
+			current = append(current, r)
+		}
+		responses = current
+	}
+	item.Responses = len(responses)
+	for _, r := range responses {
+		if r.Correct {
+			item.Correct++
+		}
+	}
+	item.PercentCorrect = percent(item.Correct, item.Responses)
+
+	if n := len(responses); n >= MinResponses {
+		sorted := make([]Response, n)
+		copy(sorted, responses)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].Score > sorted[j].Score
+		})
+		g := int(math.Round(groupShare * float64(n)))
+		item.Discrimination = percent(countCorrect(sorted[:g]), g) - percent(countCorrect(sorted[n-g:]), g)
+		item.HasDiscrimination = true
+	}
+
+	if lesson == nil || index >= len(lesson.Quiz.Questions) {
+		item.Missing = true
+		return item
+	}
+	item.LessonTitle = lesson.Title
+	item.LessonOrder = lesson.Order
+	q := lesson.Quiz.Questions[index]
+	item.Type = q.Type
+	item.Text = questionText(q)
+
+	if q.Type == "multiple_choice" || q.Type == "listen_and_choose" {
+		counts := make([]int, len(q.Options))
+		for _, r := range responses {
+			idx, err := strconv.Atoi(r.Answer)
+			if err != nil || idx < 0 || idx >= len(counts) {
+				item.Unanswered++
+				continue
+			}
+			counts[idx]++
+		}
+		for i, text := range q.Options {
+			item.Options = append(item.Options, Option{
+				Letter:  string(rune('A' + i)),
+				Text:    text,
+				Correct: i == q.Correct,
+				Count:   counts[i],
+				Percent: percent(counts[i], item.Responses),
+			})
+		}
+	}
+
+	item.Flags = flags(item)
+	return item
+}
+
+// flags lists what looks wrong with an item, once it has enough answers to
+// tell.
+func flags(item Item) []string {
+	if item.Responses < MinResponses {
+		return nil
+	}
+	var f []string
+	if item.PercentCorrect < int(hardBelow*100) {
+		f = append(f, "rarely answered correctly")
+	}
+	if item.HasDiscrimination {
+		switch {
+		case item.Discrimination < 0:
+			f = append(f, "weaker learners do better")
+		case item.Discrimination < int(weakDiscrimination*100):
+			f = append(f, "doesn't separate strong and weak learners")
+		}
+	}
+	correct := 0
+	for _, o := range item.Options {
+		if o.Correct {
+			correct = o.Count
+		}
+	}
+	for _, o := range item.Options {
+		switch {
+		case o.Correct:
+		case o.Count > correct:
+			f = append(f, "option "+o.Letter+" is picked more than the answer")
+		case o.Count == 0:
+			f = append(f, "option "+o.Letter+" is never picked")
+		}
+	}
+	return f
+}
+
+// WriteCSV writes items as CSV with a header row. Options are summarized as
+// "A*:12 B:3 C:0", with * marking the correct option.
+func WriteCSV(w io.Writer, items []Item) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"language", "lesson_id", "lesson_title", "question", "type", "text",
+		"responses", "percent_correct", "discrimination", "options", "unanswered", "flags",
+		"outdated",
+	})
+	for _, it := range items {
+		discrimination := ""
+		if it.HasDiscrimination {
+			discrimination = strconv.Itoa(it.Discrimination)
+		}
+		var options []string
+		for _, o := range it.Options {
+			mark := ""
+			if o.Correct {
+				mark = "*"
+			}
+			options = append(options, fmt.Sprintf("%s%s:%d", o.Letter, mark, o.Count))
+		}
+		unanswered := ""
+		if len(it.Options) > 0 {
+			unanswered = strconv.Itoa(it.Unanswered)
+		}
+		cw.Write([]string{
+			it.Language,
+			it.LessonID,
+			it.LessonTitle,
+			strconv.Itoa(it.Number),
+			it.Type,
+			it.Text,
+			strconv.Itoa(it.Responses),
+			strconv.Itoa(it.PercentCorrect),
+			discrimination,
+			strings.Join(options, " "),
+			unanswered,
+			strings.Join(it.Flags, "; "),
+			strconv.Itoa(it.Outdated),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// questionText is a one-line description of a question for reports.
+func questionText(q lessons.Question) string {
+	switch {
+	case q.Question != "":
+		return q.Question
+	case q.Prompt != "":
+		return q.Prompt
+	case q.Type == "match_pairs":
+		words := make([]string, len(q.Pairs))
+		for i, p := range q.Pairs {
+			words[i] = p.English
+		}
+		return "Match: " + strings.Join(words, ", ")
+	}
+	return q.Type
+}
+
+func countCorrect(responses []Response) int {
+	n := 0
+	for _, r := range responses {
+		if r.Correct {
+			n++
+		}
+	}
+	return n
+}
+
+func percent(n, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return int(math.Round(float64(n) * 100 / float64(total)))
+}
@@ -0,0 +1,253 @@
+// Package langpack loads language packs: languages added at runtime from
+// SPEAKEASY_DATA_DIR/languages instead of being compiled in.
+//
+// A pack is a directory or a .zip file laid out like a built-in language
+// package, plus a manifest:
+//
+//	language.json   lessons.Language fields, e.g. {"slug": "slovene", ...}
+//	data/*.json     lessons, in the same format as internal/lessons/*/data
+//	svg/*.svg       lesson illustrations named by each lesson's "illustration"
+//	audio/*.mp3     optional recordings named by a vocab item's "audio_override"
+//
+// A zip may hold those entries at its root or inside a single top-level
+// directory.
+//
+// A pack whose slug is already registered is rejected, unless language.json
+// sets "override": true, in which case it replaces the existing language.
+// Two packs with the same slug are never loaded together; the first in
+// directory order wins.
+package langpack
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"speakeasy/internal/lessons"
+)
+
+// manifest is the contents of language.json.
+type manifest struct {
+	lessons.Language
+
+	// Override replaces a language with the same slug that is already
+	// registered, such as a built-in one.
+	Override bool `json:"override"`
+}
+
+// Pack is a loaded language pack.
+type Pack struct {
+	Language lessons.Language
+	Source   string // path of the directory or zip it was loaded from
+	FS       fs.FS  // rooted at the pack, holding language.json
+
+	// Audio maps a word's text to its recording in FS, from the vocab items'
+	// audio_override fields.
+	Audio map[string]string
+}
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// LoadDir loads every pack in dir and registers its language. Packs that fail
+// to load or whose slug conflicts are skipped and reported in errs; a
+// missing dir is not an error.
+func LoadDir(dir string) (packs []*Pack, errs []error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []error{fmt.Errorf("language packs: %w", err)}
+	}
+
+	loaded := make(map[string]string) // slug → source, for packs in this dir
+	for _, e := range entries {
+		src := filepath.Join(dir, e.Name())
+		if !e.IsDir() && !strings.EqualFold(filepath.Ext(e.Name()), ".zip") {
+			continue
+		}
+
+		p, err := openPack(src)
+		if err != nil {
+			// Each problem in the lesson files is reported on its own
+			var lessonErrs lessons.Errors
+			if !errors.As(err, &lessonErrs) {
+				errs = append(errs, err)
+				continue
+			}
+			for _, e := range lessonErrs {
+				errs = append(errs, fmt.Errorf("language pack %s: %w", src, e))
+			}
+			continue
+		}
+		slug := p.Language.Slug
+		if prev, dup := loaded[slug]; dup {
+			errs = append(errs, fmt.Errorf("language pack %s: slug %q is already used by pack %s; skipped", src, slug, prev))
+			continue
+		}
+		if lessons.GetLanguage(slug) != nil {
+			if !p.override {
+				errs = append(errs, fmt.Errorf("language pack %s: slug %q is already registered; skipped (set \"override\": true in language.json to replace it)", src, slug))
+				continue
+			}
+			slog.Warn("language pack replaces registered language", "slug", slug, "pack", src)
+			lessons.StopDevWatch(slug)
+		}
+
+		lessons.Register(p.Language, p.lessons)
+		if info, err := os.Stat(src); err == nil && info.IsDir() {
+			lessons.SetSource(slug, lessons.Source{Dir: src, Reload: reloader(src, slug)})
+		}
+		loaded[slug] = src
+		packs = append(packs, p.Pack)
+		slog.Info("loaded language pack", "slug", slug, "pack", src, "lessons", len(p.lessons))
+	}
+	return packs, errs
+}
+
+// reloader returns a lessons.Source Reload function for a pack directory.
+// Audio overrides are read only at startup, so new recordings still need a
+// restart.
+func reloader(src, slug string) func() error {
+	return func() error {
+		p, err := openPack(src)
+		if err != nil {
+			return err
+		}
+		if p.Language.Slug != slug {
+			return fmt.Errorf("language pack %s: slug changed from %q to %q; restart to load it", src, slug, p.Language.Slug)
+		}
+		lessons.Register(p.Language, p.lessons)
+		return nil
+	}
+}
+
+// opened is a parsed pack that has not been registered yet.
+type opened struct {
+	*Pack
+	lessons  []*lessons.Lesson
+	override bool
+}
+
+// openPack reads and validates the pack at src, a directory or a .zip
+// file, without registering it.
+func openPack(src string) (*opened, error) {
+	fsys, err := packFS(src)
+	if err != nil {
+		return nil, fmt.Errorf("language pack %s: %w", src, err)
+	}
+
+	data, err := fs.ReadFile(fsys, "language.json")
+	if err != nil {
+		return nil, fmt.Errorf("language pack %s: %w", src, err)
+	}
+	var m manifest
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("language pack %s: parse language.json: %w", src, err)
+	}
+	lang := m.Language
+	switch {
+	case !slugPattern.MatchString(lang.Slug):
+		return nil, fmt.Errorf("language pack %s: slug %q must be lowercase letters, digits and dashes", src, lang.Slug)
+	case lang.DisplayName == "":
+		return nil, fmt.Errorf("language pack %s: display_name is required", src)
+	case lang.TTSCode == "":
+		return nil, fmt.Errorf("language pack %s: tts_code is required", src)
+	}
+
+	result, err := lessons.LoadFromFS(fsys, lang)
+	if err != nil {
+		return nil, fmt.Errorf("language pack %s: %w", src, err)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("language pack %s: no lessons in data/", src)
+	}
+
+	audio := make(map[string]string)
+	for _, l := range result {
+		if l.Illustration != "" {
+			l.IllustrationURL = "/languages/" + lang.Slug + "/svg/" + l.Illustration
+		}
+		for _, section := range l.Sections {
+			for _, item := range section.Items {
+				if item.AudioOverride == nil || *item.AudioOverride == "" {
+					continue
+				}
+				name := path.Join("audio", *item.AudioOverride)
+				if _, err := fs.Stat(fsys, name); err != nil {
+					return nil, fmt.Errorf("language pack %s: lesson %q word %q: audio_override: %w", src, l.ID, item.ID, err)
+				}
+				audio[item.TargetPrimary] = name
+				if item.TargetAlt != "" {
+					audio[item.TargetAlt] = name
+				}
+			}
+		}
+	}
+
+	return &opened{
+		Pack:     &Pack{Language: lang, Source: src, FS: fsys, Audio: audio},
+		lessons:  result,
+		override: m.Override,
+	}, nil
+}
+
+// packFS opens a pack directory or zip. A zip whose only top-level entry is
+// a directory is rooted inside it.
+func packFS(src string) (fs.FS, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return os.DirFS(src), nil
+	}
+
+	// The reader stays open for the life of the server
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fs.Stat(zr, "language.json"); err == nil {
+		return zr, nil
+	}
+	entries, err := fs.ReadDir(zr, ".")
+	if err == nil && len(entries) == 1 && entries[0].IsDir() {
+		return fs.Sub(zr, entries[0].Name())
+	}
+	return zr, nil
+}
+
+// AssetHandler serves the packs' illustrations at /languages/{slug}/svg/{file}.
+func AssetHandler(packs []*Pack) http.Handler {
+	bySlug := make(map[string]*Pack, len(packs))
+	for _, p := range packs {
+		bySlug[p.Language.Slug] = p
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// parts[0] = "languages", parts[1] = slug, parts[2] = "svg", parts[3] = file
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) != 4 || parts[2] != "svg" || !fs.ValidPath(parts[3]) {
+			http.NotFound(w, r)
+			return
+		}
+		p, ok := bySlug[parts[1]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFileFS(w, r, p.FS, path.Join("svg", parts[3]))
+	})
+}
@@ -0,0 +1,81 @@
+package lessons
+
+import "math/rand/v2"
+
+// Checkpoint configures a checkpoint lesson: a unit test over every
+// published lesson before it. Its quiz asks its own questions, if any, then
+// Sample questions drawn from the covered lessons' quizzes and, with
+// quiz.generate, questions generated from their vocabulary, all different on
+// every attempt. The lesson's pass_score is the checkpoint's pass mark.
+type Checkpoint struct {
+	Sample int `json:"sample"` // questions drawn from the covered lessons' quizzes per attempt
+}
+
+// Randomized reports whether the lesson's quiz differs between attempts, so
+// an attempt has to be graded against the questions generated for it (see
+// ForAttempt) and question numbers past the hand-written ones mean nothing
+// across attempts.
+func (l *Lesson) Randomized() bool {
+	return l.Quiz.Generate != nil || l.Checkpoint != nil
+}
+
+// Covered returns the lessons a checkpoint tests: the published lessons
+// ordered before it, other checkpoints aside. It returns nil for an ordinary
+// lesson.
+func Covered(slug string, l *Lesson) []*Lesson {
+	if l == nil || l.Checkpoint == nil {
+		return nil
+	}
+	var covered []*Lesson
+	for _, other := range GetLessons(slug, false) {
+		if other.Order < l.Order && other.Checkpoint == nil {
+			covered = append(covered, other)
+		}
+	}
+	return covered
+}
+
+// sampleQuestions draws up to n hand-written questions from the covered
+// lessons, spread as evenly over them as their quizzes allow: each round
+// takes one question from every lesson that has any left, in a shuffled
+// order. The same seed always draws the same questions.
+func sampleQuestions(covered []*Lesson, n int, seed uint64) []Question {
+	rng := rand.New(rand.NewPCG(seed, ^seed))
+	pools := make([][]Question, 0, len(covered))
+	for _, l := range covered {
+		pool := append([]Question(nil), l.Quiz.Questions...)
+		rng.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+		pools = append(pools, pool)
+	}
+
+	var sample []Question
+	for len(sample) < n {
+		took := false
+		for _, i := range rng.Perm(len(pools)) {
+			if len(sample) == n || len(pools[i]) == 0 {
+				continue
+			}
+			sample = append(sample, pools[i][0])
+			pools[i] = pools[i][1:]
+			took = true
+		}
+		if !took {
+			break
+		}
+	}
+	return sample
+}
+
+// coveredVocab returns a lesson holding the vocabulary of the covered
+// lessons, for generating a checkpoint's questions from.
+func coveredVocab(covered []*Lesson) *Lesson {
+	vocab := &Lesson{}
+	for _, l := range covered {
+		for _, s := range l.Sections {
+			if s.Type == "vocab" {
+				vocab.Sections = append(vocab.Sections, s)
+			}
+		}
+	}
+	return vocab
+}
@@ -0,0 +1,148 @@
+package lessons
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Development mode reads lesson JSON from the source tree instead of the
+// copies embedded in the binary, and reloads a language whenever one of its
+// files changes, so content fixes show up on the next page load without a
+// rebuild. It is switched on with SPEAKEASY_DEV=true. Lessons are looked for
+// in SPEAKEASY_LESSONS_DIR/{slug}/data, where SPEAKEASY_LESSONS_DIR defaults
+// to internal/lessons relative to the working directory.
+
+// DevMode reports whether SPEAKEASY_DEV=true is set.
+func DevMode() bool {
+	return strings.EqualFold(os.Getenv("SPEAKEASY_DEV"), "true")
+}
+
+// devSource is a language loaded from disk in development mode.
+type devSource struct {
+	lang      Language
+	dir       string // holds data/
+	signature string // of data/*.json when last loaded
+}
+
+var (
+	devMu      sync.Mutex
+	devSources []*devSource
+)
+
+// devLanguageDir returns the on-disk directory for a language in development
+// mode. ok is false outside development mode or when the directory is
+// missing, in which case the embedded lessons are used.
+func devLanguageDir(slug string) (dir string, ok bool) {
+	if !DevMode() {
+		return "", false
+	}
+	root := os.Getenv("SPEAKEASY_LESSONS_DIR")
+	if root == "" {
+		root = filepath.Join("internal", "lessons")
+	}
+	dir = filepath.Join(root, slug)
+	if info, err := os.Stat(filepath.Join(dir, "data")); err != nil || !info.IsDir() {
+		slog.Warn("dev mode: no lesson directory on disk, using embedded lessons", "language", slug, "dir", dir)
+		return "", false
+	}
+	return dir, true
+}
+
+// watchDev remembers a disk-loaded language for WatchDev, and makes it
+// editable in the lesson editor.
+func watchDev(lang Language, dir string) {
+	devMu.Lock()
+	defer devMu.Unlock()
+	devSources = append(devSources, &devSource{lang: lang, dir: dir, signature: dataSignature(dir)})
+	slog.Info("dev mode: loading lessons from disk", "language", lang.Slug, "dir", dir)
+
+	SetSource(lang.Slug, Source{Dir: dir, Reload: func() error {
+		result, err := LoadFromFS(os.DirFS(dir), lang)
+		if err != nil {
+			return err
+		}
+		Register(lang, result)
+		return nil
+	}})
+}
+
+// StopDevWatch stops development mode reloading a language from disk, and
+// drops its editor Source, for a language pack that replaces the language.
+// Otherwise editing a built-in file would register the built-in lessons over
+// the pack's.
+func StopDevWatch(slug string) {
+	devMu.Lock()
+	var kept []*devSource
+	for _, src := range devSources {
+		if src.lang.Slug != slug {
+			kept = append(kept, src)
+		}
+	}
+	devSources = kept
+	devMu.Unlock()
+
+	sourcesMu.Lock()
+	delete(sources, slug)
+	sourcesMu.Unlock()
+}
+
+// WatchDev polls the lesson files of every disk-loaded language and reloads a
+// language when they change. A reload that fails to parse or validate is
+// logged and the previous version stays registered. It never returns, so run
+// it in its own goroutine.
+func WatchDev(interval time.Duration) {
+	for range time.Tick(interval) {
+		devMu.Lock()
+		sources := append([]*devSource(nil), devSources...)
+		devMu.Unlock()
+
+		for _, src := range sources {
+			sig := dataSignature(src.dir)
+			if sig == src.signature {
+				continue
+			}
+			// Remember the new state even if it fails, so a broken file is
+			// reported once rather than on every tick until it is fixed.
+			src.signature = sig
+
+			result, err := LoadFromFS(os.DirFS(src.dir), src.lang)
+			if err != nil {
+				for _, e := range Split(err) {
+					slog.Error("dev mode: lesson reload failed, keeping previous version",
+						"language", src.lang.Slug, "error", e)
+				}
+				continue
+			}
+			Register(src.lang, result)
+			slog.Info("dev mode: reloaded lessons", "language", src.lang.Slug, "lessons", len(result))
+		}
+	}
+}
+
+// dataSignature summarizes the name, size and modification time of every
+// lesson file in dir/data, so adding, removing or editing one changes it.
+func dataSignature(dir string) string {
+	entries, err := os.ReadDir(filepath.Join(dir, "data"))
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	var parts []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d:%d", e.Name(), info.Size(), info.ModTime().UnixNano()))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "|")
+}
@@ -0,0 +1,127 @@
+package lessons
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+)
+
+// Encode formats a lesson as a lesson file: JSON indented by two spaces, in
+// the field order of the Go types. Unlike json.Marshal it writes the fields a
+// section or question type requires even when they are zero, such as a
+// "correct" of 0, and leaves out the fields of other types, so a valid lesson
+// always encodes to a file that Parse accepts.
+func Encode(l *Lesson) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(encodeValue(reflect.ValueOf(l))); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeValue converts v to something json can encode in the lesson file
+// layout.
+func encodeValue(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return encodeValue(v.Elem())
+
+	case reflect.Slice:
+		// An absent list is written as [] so required lists are never null
+		elems := make([]any, v.Len())
+		for i := range elems {
+			elems[i] = encodeValue(v.Index(i))
+		}
+		return elems
+
+	case reflect.Struct:
+		t := v.Type()
+		vs, hasVariants := variants[t]
+		var governed, uses, required map[string]bool
+		if hasVariants {
+			governed = variantFields(vs.types)
+			uses, required = make(map[string]bool), make(map[string]bool)
+			typ := vs.types[v.FieldByName("Type").String()]
+			for _, name := range typ.required {
+				uses[name], required[name] = true, true
+			}
+			for _, name := range typ.optional {
+				uses[name] = true
+			}
+		}
+
+		var obj object
+		for _, f := range jsonFields(t) {
+			fv := v.Field(f.index)
+			var include bool
+			switch {
+			case governed[f.name]:
+				include = required[f.name] || (uses[f.name] && !fv.IsZero())
+			case f.typ.Kind() == reflect.Pointer:
+				// Written as null when unset, like the existing files, unless
+				// the field is omitempty
+				include = !f.omitempty || !fv.IsNil()
+			default:
+				include = f.required || !fv.IsZero()
+			}
+			if include {
+				obj = append(obj, member{f.name, encodeValue(fv)})
+			}
+		}
+		return obj
+	}
+	return v.Interface()
+}
+
+// object is a JSON object that keeps its keys in order.
+type object []member
+
+type member struct {
+	key   string
+	value any
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := enc.Encode(m.key); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1) // Encode adds a newline
+		buf.WriteByte(':')
+		if err := enc.Encode(m.value); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// contentVersion hashes the lesson's canonical encoding, so reformatting the
+// file or reordering its keys keeps the version. The "$schema" key and the
+// draft flag are left out: publishing a lesson doesn't change its content.
+func contentVersion(l *Lesson) (string, error) {
+	c := *l
+	c.Schema, c.Draft = "", false
+	data, err := Encode(&c)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:6]), nil
+}
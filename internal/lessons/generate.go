@@ -0,0 +1,383 @@
+package lessons
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultGeneratedTypes are the question types a quiz generates when its
+// Generate config names none.
+var DefaultGeneratedTypes = []string{"multiple_choice", "listen_and_choose", "type_answer"}
+
+// GeneratedTypes returns the question types that can be generated, in order.
+func GeneratedTypes() []string {
+	return []string{"listen_and_choose", "match_pairs", "multiple_choice", "type_answer"}
+}
+
+const (
+	choiceOptions = 4 // options in a generated multiple-choice question
+	listenOptions = 3 // options in a generated listen-and-choose question
+	maxPairs      = 5 // pairs in a generated match-pairs question
+	minPairs      = 3 // fewer pairs would give the answer away
+)
+
+// questionForm is one way of asking about a word. Reverse questions show
+// the target-language word and ask for the English.
+type questionForm struct {
+	typ     string
+	reverse bool
+}
+
+// ForAttempt returns the lesson as quizzed in one attempt. A lesson whose
+// quiz is the same every time is returned as it is. Otherwise the copy
+// returned asks the lesson's own questions followed by the ones seed picks:
+// for a checkpoint, questions sampled from the lessons it covers; then, with
+// quiz.generate, questions generated from the lesson's vocabulary (or, for a
+// checkpoint, the covered lessons'), with distractors from the language's
+// published lessons. The hand-written questions keep their numbers, so their
+// answers can be compared across attempts.
+func ForAttempt(slug string, l *Lesson, seed uint64) *Lesson {
+	if l == nil || !l.Randomized() {
+		return l
+	}
+	lang := GetLanguage(slug)
+	if lang == nil {
+		return l
+	}
+
+	attempt := *l
+	attempt.Quiz.Questions = append([]Question(nil), l.Quiz.Questions...)
+	source := l
+	if l.Checkpoint != nil {
+		covered := Covered(slug, l)
+		attempt.Quiz.Questions = append(attempt.Quiz.Questions, sampleQuestions(covered, l.Checkpoint.Sample, seed)...)
+		source = coveredVocab(covered)
+	}
+	if l.Quiz.Generate != nil {
+		var pool []VocabItem
+		for _, w := range GetWords(slug) {
+			pool = append(pool, w.VocabItem)
+		}
+		for _, q := range GenerateQuestions(*lang, source, pool, *l.Quiz.Generate, seed) {
+			prepareQuestion(source, &q)
+			attempt.Quiz.Questions = append(attempt.Quiz.Questions, q)
+		}
+	}
+	return &attempt
+}
+
+// QuizVersion identifies the questions an attempt at l's quiz can ask, for
+// telling answers to an outdated quiz apart. For most lessons it is
+// l.Version, but generated questions draw distractors from every published
+// lesson's vocabulary and a checkpoint samples the lessons it covers, so the
+// same seed gives different questions once one of those lessons is edited.
+// Their versions are folded in for such quizzes.
+func QuizVersion(slug string, l *Lesson) string {
+	if l == nil {
+		return ""
+	}
+	if !l.Randomized() {
+		return l.Version
+	}
+	sources := Covered(slug, l)
+	if l.Quiz.Generate != nil {
+		sources = GetLessons(slug, false)
+	}
+	h := sha256.New()
+	h.Write([]byte(l.Version))
+	for _, s := range sources {
+		fmt.Fprintf(h, "\n%s:%s", s.ID, s.Version)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:6])
+}
+
+// GenerateQuestions builds up to g.Count questions about the vocabulary of
+// l, cycling through g.Types and, for multiple choice and typed answers,
+// both directions: English to the target language and back. Distractors are
+// the most plausible of the lesson's other words and the words in pool, the
+// rest of the language; words sharing the answer's English or target text
+// are never used, since they would be right too.
+//
+// The questions depend only on the arguments, so the same seed always gives
+// the same quiz. Derived fields such as AudioText are left empty.
+func GenerateQuestions(lang Language, l *Lesson, pool []VocabItem, g Generate, seed uint64) []Question {
+	var words []VocabItem
+	for _, s := range l.Sections {
+		for _, item := range s.Items {
+			if item.English != "" && item.TargetPrimary != "" {
+				words = append(words, item)
+			}
+		}
+	}
+	if len(words) == 0 || g.Count <= 0 {
+		return nil
+	}
+	types := g.Types
+	if len(types) == 0 {
+		types = DefaultGeneratedTypes
+	}
+
+	gen := &generator{lang: lang, words: words, rng: rand.New(rand.NewPCG(seed, seed))}
+	own := make(map[string]bool, len(words))
+	for _, w := range words {
+		gen.candidates = append(gen.candidates, candidate{w, true})
+		own[w.ID] = true
+	}
+	for _, w := range pool {
+		if !own[w.ID] {
+			gen.candidates = append(gen.candidates, candidate{w, false})
+		}
+	}
+
+	var forms []questionForm
+	for _, typ := range types {
+		forms = append(forms, questionForm{typ, false})
+		if typ == "multiple_choice" || typ == "type_answer" {
+			forms = append(forms, questionForm{typ, true})
+		}
+	}
+	gen.rng.Shuffle(len(forms), func(i, j int) { forms[i], forms[j] = forms[j], forms[i] })
+
+	// Ask each word once before any is asked again, in a fresh order each
+	// round, and give up once every word has been tried in every form.
+	var (
+		questions []Question
+		order     []int
+		asked     = make(map[string]bool)
+	)
+	for i := 0; len(questions) < g.Count && i < len(words)*len(forms); i++ {
+		if i%len(words) == 0 {
+			order = gen.rng.Perm(len(words))
+		}
+		w := words[order[i%len(words)]]
+		f := forms[i%len(forms)]
+		key := fmt.Sprintf("%s/%s/%t", w.ID, f.typ, f.reverse)
+		if asked[key] {
+			continue
+		}
+		if q, ok := gen.question(f, w); ok {
+			asked[key] = true
+			questions = append(questions, q)
+		}
+	}
+	return questions
+}
+
+// generator holds what the questions of one quiz are built from.
+type generator struct {
+	lang       Language
+	words      []VocabItem // the lesson's words
+	candidates []candidate // possible distractors
+	rng        *rand.Rand
+}
+
+// candidate is a word that can serve as a distractor.
+type candidate struct {
+	VocabItem
+	own bool // from the lesson being quizzed rather than another one
+}
+
+func englishText(v VocabItem) string { return v.English }
+func targetText(v VocabItem) string  { return v.TargetPrimary }
+
+// question asks about w in form f, or returns false if it can't, e.g. for
+// lack of distractors.
+func (g *generator) question(f questionForm, w VocabItem) (Question, bool) {
+	switch {
+	case f.typ == "multiple_choice" && !f.reverse:
+		options, correct, ok := g.choices(w, targetText, choiceOptions)
+		return Question{
+			Type:     "multiple_choice",
+			Question: fmt.Sprintf("How do you say '%s' in %s?", w.English, g.lang.DisplayName),
+			Options:  options,
+			Correct:  correct,
+			WordID:   w.ID,
+		}, ok
+
+	case f.typ == "multiple_choice":
+		options, correct, ok := g.choices(w, englishText, choiceOptions)
+		return Question{
+			Type:     "multiple_choice",
+			Question: fmt.Sprintf("What does '%s' mean?", w.TargetPrimary),
+			Options:  options,
+			Correct:  correct,
+			WordID:   w.ID,
+		}, ok
+
+	case f.typ == "listen_and_choose":
+		options, correct, ok := g.choices(w, englishText, listenOptions)
+		return Question{
+			Type:    "listen_and_choose",
+			Options: options,
+			Correct: correct,
+			WordID:  w.ID,
+		}, ok
+
+	case f.typ == "type_answer" && !f.reverse:
+		answers := acceptedAnswers(w.TargetPrimary)
+		if w.TargetAlt != "" {
+			answers = appendNew(answers, acceptedAnswers(w.TargetAlt)...)
+		}
+		return Question{
+			Type:           "type_answer",
+			Prompt:         fmt.Sprintf("Type the %s for '%s'", g.lang.DisplayName, w.English),
+			CorrectAnswers: answers,
+			WordID:         w.ID,
+		}, true
+
+	case f.typ == "type_answer":
+		return Question{
+			Type:           "type_answer",
+			Prompt:         fmt.Sprintf("Type the English for '%s'", w.TargetPrimary),
+			CorrectAnswers: acceptedAnswers(w.English),
+			WordID:         w.ID,
+		}, true
+
+	case f.typ == "match_pairs":
+		return g.matchPairs(w)
+	}
+	return Question{}, false
+}
+
+// choices returns up to size options for answer: its own text, as given by
+// text, among the most plausible distractors, and the index of the answer.
+// ok is false if there is no distractor at all.
+func (g *generator) choices(answer VocabItem, text func(VocabItem) string, size int) (options []string, correct int, ok bool) {
+	want := text(answer)
+	type scored struct {
+		text  string
+		score int
+	}
+	var pool []scored
+	seen := map[string]bool{strings.ToLower(want): true}
+	for _, i := range g.rng.Perm(len(g.candidates)) {
+		c := g.candidates[i]
+		t := text(c.VocabItem)
+		if t == "" || seen[strings.ToLower(t)] ||
+			strings.EqualFold(c.English, answer.English) || strings.EqualFold(c.TargetPrimary, answer.TargetPrimary) {
+			continue
+		}
+		seen[strings.ToLower(t)] = true
+		score := plausibility(answer, c.VocabItem, text)
+		if c.own {
+			score++
+		}
+		pool = append(pool, scored{t, score})
+	}
+	if len(pool) == 0 {
+		return nil, 0, false
+	}
+	// Stable, so equally plausible distractors keep their shuffled order
+	sort.SliceStable(pool, func(i, j int) bool { return pool[i].score > pool[j].score })
+	if len(pool) > size-1 {
+		pool = pool[:size-1]
+	}
+
+	correct = g.rng.IntN(len(pool) + 1)
+	for i, d := range pool {
+		if i == correct {
+			options = append(options, want)
+		}
+		options = append(options, d.text)
+	}
+	if correct == len(pool) {
+		options = append(options, want)
+	}
+	return options, correct, true
+}
+
+// plausibility scores how good a distractor other is for answer: numbers go
+// with numbers, and options of the same shape (words, length, first letter,
+// question or not) don't give the answer away.
+func plausibility(answer, other VocabItem, text func(VocabItem) string) int {
+	a, b := text(answer), text(other)
+	score := 0
+	if hasDigit(answer.English) == hasDigit(other.English) {
+		score += 3
+	}
+	if len(strings.Fields(a)) == len(strings.Fields(b)) {
+		score += 2
+	}
+	switch d := utf8.RuneCountInString(a) - utf8.RuneCountInString(b); {
+	case d >= -2 && d <= 2:
+		score += 2
+	case d >= -5 && d <= 5:
+		score++
+	}
+	if strings.HasSuffix(a, "?") == strings.HasSuffix(b, "?") {
+		score++
+	}
+	ra, _ := utf8.DecodeRuneInString(a)
+	rb, _ := utf8.DecodeRuneInString(b)
+	if unicode.ToLower(ra) == unicode.ToLower(rb) {
+		score++
+	}
+	return score
+}
+
+func hasDigit(s string) bool {
+	return strings.IndexFunc(s, unicode.IsDigit) >= 0
+}
+
+// matchPairs matches w and up to maxPairs-1 other words of the lesson whose
+// English and target texts are all different, so every pair has exactly
+// one match.
+func (g *generator) matchPairs(w VocabItem) (Question, bool) {
+	q := Question{Type: "match_pairs"}
+	english, target := make(map[string]bool), make(map[string]bool)
+	add := func(v VocabItem) {
+		e, t := strings.ToLower(v.English), strings.ToLower(v.TargetPrimary)
+		if len(q.Pairs) == maxPairs || english[e] || target[t] {
+			return
+		}
+		english[e], target[t] = true, true
+		q.Pairs = append(q.Pairs, Pair{English: v.English, Target: v.TargetPrimary, WordID: v.ID})
+	}
+	add(w)
+	for _, i := range g.rng.Perm(len(g.words)) {
+		add(g.words[i])
+	}
+	return q, len(q.Pairs) >= minPairs
+}
+
+// parenthetical matches a note such as " (formal)" or " (1)".
+var parenthetical = regexp.MustCompile(`\s*\([^)]*\)`)
+
+// acceptedAnswers returns the answers a typed question takes for text: the
+// text itself, without its notes in parentheses, each of its alternatives
+// separated by "/", and each of those without trailing punctuation. "How are
+// you? (formal)" accepts "How are you?" and "How are you" too.
+func acceptedAnswers(text string) []string {
+	answers := []string{text}
+	bare := strings.TrimSpace(parenthetical.ReplaceAllString(text, ""))
+	for _, alt := range append([]string{bare}, strings.Split(bare, "/")...) {
+		alt = strings.TrimSpace(alt)
+		answers = appendNew(answers, alt, strings.TrimRight(alt, " ?!.,:;¿¡"))
+	}
+	return answers
+}
+
+// appendNew appends the values not already in list, ignoring case and
+// skipping empty ones.
+func appendNew(list []string, values ...string) []string {
+next:
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		for _, have := range list {
+			if strings.EqualFold(have, v) {
+				continue next
+			}
+		}
+		list = append(list, v)
+	}
+	return list
+}
@@ -1,10 +1,12 @@
 package lessons
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/fs"
+	"os"
 	"sort"
+	"strings"
+	"sync"
 )
 
 // RegisterFromFS loads all lesson JSON files from the "data/" subdirectory of
@@ -19,55 +21,179 @@ import (
 //	func init() {
 //	    lessons.RegisterFromFS(lessonData, lessons.Language{...})
 //	}
+//
+// In development mode (see DevMode) the lessons are read from the language's
+// source directory on disk instead, and reloaded when they change.
+//
+// Problems in the lesson files don't panic, since init() has no way to
+// report them; they are kept for LoadErrors and the language is left out.
 func RegisterFromFS(lessonFS fs.FS, lang Language) {
+	if dir, ok := devLanguageDir(lang.Slug); ok {
+		lessonFS = os.DirFS(dir)
+		watchDev(lang, dir)
+	}
+
+	result, err := LoadFromFS(lessonFS, lang)
+	if err != nil {
+		loadErrsMu.Lock()
+		loadErrs = append(loadErrs, Split(err)...)
+		loadErrsMu.Unlock()
+		return
+	}
+	Register(lang, result)
+}
+
+var (
+	loadErrsMu sync.Mutex
+	loadErrs   []error
+)
+
+// LoadErrors returns the problems found by RegisterFromFS, one per file and
+// JSON path. A language with any problem is not registered, so callers should
+// check this once the language packages are imported.
+func LoadErrors() []error {
+	loadErrsMu.Lock()
+	defer loadErrsMu.Unlock()
+	return append([]error(nil), loadErrs...)
+}
+
+// LoadFromFS parses and validates every lesson file under "data/". Problems in
+// the files are returned together as Errors rather than stopping at the first.
+func LoadFromFS(lessonFS fs.FS, lang Language) ([]*Lesson, error) {
 	entries, err := fs.ReadDir(lessonFS, "data")
 	if err != nil {
-		panic(fmt.Sprintf("speakeasy: load language %q: %v", lang.Slug, err))
+		return nil, fmt.Errorf("load language %q: %w", lang.Slug, err)
 	}
 
-	var result []*Lesson
+	var (
+		result []*Lesson
+		errs   Errors
+	)
 	for _, entry := range entries {
-		if entry.IsDir() {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
 			continue
 		}
 		data, err := fs.ReadFile(lessonFS, "data/"+entry.Name())
 		if err != nil {
-			panic(fmt.Sprintf("speakeasy: load %s/%s: %v", lang.Slug, entry.Name(), err))
+			return nil, fmt.Errorf("load %s/%s: %w", lang.Slug, entry.Name(), err)
+		}
+		file := lang.Slug + "/" + entry.Name()
+		lesson, lessonErrs := parseLesson(file, data)
+		if len(lessonErrs) > 0 {
+			errs = append(errs, lessonErrs...)
+			continue
 		}
-		var lesson Lesson
-		if err := json.Unmarshal(data, &lesson); err != nil {
-			panic(fmt.Sprintf("speakeasy: parse %s/%s: %v", lang.Slug, entry.Name(), err))
+		if lesson.Illustration != "" {
+			lesson.IllustrationURL = "/static/svg/" + lesson.Illustration
 		}
 
-		// Compute derived fields for quiz questions
 		for i := range lesson.Quiz.Questions {
-			q := &lesson.Quiz.Questions[i]
-			switch q.Type {
-			case "listen_and_choose":
-				if q.WordID != "" {
-					q.AudioText = findWordInLesson(&lesson, q.WordID)
-				}
-				// Fallback: use the correct option text if word not found
-				if q.AudioText == "" && len(q.Options) > q.Correct {
-					q.AudioText = q.Options[q.Correct]
-				}
-			case "match_pairs":
-				// Shuffle = deterministic reversal so GET and POST are consistent
-				q.ShuffledTarget = make([]string, len(q.Pairs))
-				for j, p := range q.Pairs {
-					q.ShuffledTarget[len(q.Pairs)-1-j] = p.Target
-				}
-			}
+			prepareQuestion(lesson, &lesson.Quiz.Questions[i])
 		}
 
-		result = append(result, &lesson)
+		if lesson.Version, err = contentVersion(lesson); err != nil {
+			return nil, fmt.Errorf("load %s: %w", file, err)
+		}
+		lesson.File = entry.Name()
+		result = append(result, lesson)
+	}
+
+	seen := make(map[string]string, len(result))
+	for _, l := range result {
+		if prev, dup := seen[l.ID]; dup {
+			errs = append(errs, &FieldError{File: lang.Slug + "/" + l.File, Path: "$.id", Message: fmt.Sprintf("duplicate lesson id %q, also used by %s", l.ID, prev)})
+			continue
+		}
+		seen[l.ID] = l.File
+	}
+	if len(errs) > 0 {
+		return nil, errs
 	}
 
 	sort.Slice(result, func(i, j int) bool {
 		return result[i].Order < result[j].Order
 	})
+	return result, nil
+}
 
-	Register(lang, result)
+// prepareQuestion computes the derived fields of a quiz question of lesson.
+func prepareQuestion(lesson *Lesson, q *Question) {
+	q.TrackedWordID = q.WordID
+	switch q.Type {
+	case "listen_and_choose":
+		if q.WordID != "" {
+			q.AudioText = findWordInLesson(lesson, q.WordID)
+		}
+		// Fallback: use the correct option text if word not found
+		if q.AudioText == "" && len(q.Options) > q.Correct {
+			q.AudioText = q.Options[q.Correct]
+		}
+	case "type_answer":
+		if q.TrackedWordID == "" {
+			for _, answer := range q.CorrectAnswers {
+				if id := findWordIDByTarget(lesson, answer); id != "" {
+					q.TrackedWordID = id
+					break
+				}
+			}
+		}
+	case "match_pairs":
+		// Shuffle = deterministic reversal so GET and POST are consistent
+		q.ShuffledTarget = make([]string, len(q.Pairs))
+		for j, p := range q.Pairs {
+			q.ShuffledTarget[len(q.Pairs)-1-j] = p.Target
+		}
+		for j := range q.Pairs {
+			p := &q.Pairs[j]
+			p.TrackedWordID = p.WordID
+			if p.TrackedWordID == "" {
+				p.TrackedWordID = findWordIDByTarget(lesson, p.Target)
+			}
+			if p.TrackedWordID == "" {
+				p.TrackedWordID = findWordIDByEnglish(lesson, p.English)
+			}
+		}
+	}
+}
+
+// findWordIDByTarget returns the ID of the lesson vocab item whose primary or
+// alternate script matches text, ignoring case and trailing punctuation.
+func findWordIDByTarget(lesson *Lesson, text string) string {
+	want := normalizeWord(text)
+	if want == "" {
+		return ""
+	}
+	for _, section := range lesson.Sections {
+		for _, item := range section.Items {
+			if normalizeWord(item.TargetPrimary) == want || (item.TargetAlt != "" && normalizeWord(item.TargetAlt) == want) {
+				return item.ID
+			}
+		}
+	}
+	return ""
+}
+
+// findWordIDByEnglish returns the ID of the lesson vocab item whose English
+// text matches text, ignoring case and trailing punctuation.
+func findWordIDByEnglish(lesson *Lesson, text string) string {
+	want := normalizeWord(text)
+	if want == "" {
+		return ""
+	}
+	for _, section := range lesson.Sections {
+		for _, item := range section.Items {
+			if normalizeWord(item.English) == want {
+				return item.ID
+			}
+		}
+	}
+	return ""
+}
+
+// normalizeWord lowercases s and strips surrounding spaces and trailing
+// punctuation so "Apa kabar?" and "apa kabar" compare equal.
+func normalizeWord(s string) string {
+	return strings.ToLower(strings.TrimRight(strings.TrimSpace(s), " ?!.,:;¿¡"))
 }
 
 func findWordInLesson(lesson *Lesson, wordID string) string {
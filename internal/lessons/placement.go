@@ -0,0 +1,25 @@
+package lessons
+
+// PlacementStageSize is the number of questions a placement test asks about
+// each lesson.
+const PlacementStageSize = 3
+
+// PlacementLessons returns the lessons a placement test has a stage for, in
+// order: the published lessons with a quiz, checkpoints aside.
+func PlacementLessons(slug string) []*Lesson {
+	var stages []*Lesson
+	for _, l := range GetLessons(slug, false) {
+		if l.Checkpoint == nil && (len(l.Quiz.Questions) > 0 || l.Quiz.Generate != nil) {
+			stages = append(stages, l)
+		}
+	}
+	return stages
+}
+
+// PlacementQuestions returns the questions of a placement test's stage on
+// lesson l: up to PlacementStageSize of the questions its quiz would ask,
+// drawn by the test's seed. Each stage draws differently from the same seed.
+func PlacementQuestions(slug string, l *Lesson, stage int, seed uint64) []Question {
+	seed += uint64(stage)
+	return sampleQuestions([]*Lesson{ForAttempt(slug, l, seed)}, PlacementStageSize, seed)
+}
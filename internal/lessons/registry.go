@@ -11,17 +11,30 @@ var (
 )
 
 type registeredLanguage struct {
-	config  Language
-	lessons []*Lesson
-	byID    map[string]*Lesson
+	config    Language
+	lessons   []*Lesson
+	published []*Lesson // lessons without drafts
+	byID      map[string]*Lesson
 }
 
 // Register adds a language and its lessons to the global registry.
 // Typically called from a language package's init() function.
+//
+// Lessons without their own pass_score inherit the language's PassScore,
+// which in turn defaults to DefaultPassScore.
 func Register(lang Language, lessons []*Lesson) {
 	mu.Lock()
 	defer mu.Unlock()
 
+	if lang.PassScore == 0 {
+		lang.PassScore = DefaultPassScore
+	}
+	for _, l := range lessons {
+		if l.PassScore == 0 {
+			l.PassScore = lang.PassScore
+		}
+	}
+
 	sorted := make([]*Lesson, len(lessons))
 	copy(sorted, lessons)
 	sort.Slice(sorted, func(i, j int) bool {
@@ -29,14 +42,19 @@ func Register(lang Language, lessons []*Lesson) {
 	})
 
 	byID := make(map[string]*Lesson, len(sorted))
+	var published []*Lesson
 	for _, l := range sorted {
 		byID[l.ID] = l
+		if !l.Draft {
+			published = append(published, l)
+		}
 	}
 
 	languages[lang.Slug] = &registeredLanguage{
-		config:  lang,
-		lessons: sorted,
-		byID:    byID,
+		config:    lang,
+		lessons:   sorted,
+		published: published,
+		byID:      byID,
 	}
 }
 
@@ -68,7 +86,8 @@ func GetLanguage(slug string) *Language {
 	return &lang
 }
 
-// GetAllLessons returns all lessons for a language, sorted by order.
+// GetAllLessons returns all lessons for a language, drafts included, sorted
+// by order.
 func GetAllLessons(slug string) []*Lesson {
 	mu.RLock()
 	defer mu.RUnlock()
@@ -80,6 +99,23 @@ func GetAllLessons(slug string) []*Lesson {
 	return rl.lessons
 }
 
+// GetLessons returns the lessons for a language sorted by order, leaving out
+// draft lessons unless drafts is true. Learners only ever see published
+// lessons; admins see drafts too.
+func GetLessons(slug string, drafts bool) []*Lesson {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	rl, ok := languages[slug]
+	if !ok {
+		return nil
+	}
+	if drafts {
+		return rl.lessons
+	}
+	return rl.published
+}
+
 // GetLesson returns a specific lesson by language slug and lesson ID.
 func GetLesson(slug, id string) *Lesson {
 	mu.RLock()
@@ -92,7 +128,8 @@ func GetLesson(slug, id string) *Lesson {
 	return rl.byID[id]
 }
 
-// GetNextLessonID returns the ID of the next lesson after currentID, or "" if none.
+// GetNextLessonID returns the ID of the next published lesson after
+// currentID, or "" if none. Draft lessons are skipped.
 func GetNextLessonID(slug, currentID string) string {
 	mu.RLock()
 	defer mu.RUnlock()
@@ -105,10 +142,75 @@ func GetNextLessonID(slug, currentID string) string {
 	if !ok {
 		return ""
 	}
-	for _, l := range rl.lessons {
-		if l.Order == current.Order+1 {
+	for _, l := range rl.published {
+		if l.Order > current.Order {
 			return l.ID
 		}
 	}
 	return ""
 }
+
+// GetPreviousLessonID returns the ID of the published lesson before
+// currentID, or "" if there is none. Draft lessons are skipped.
+func GetPreviousLessonID(slug, currentID string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	rl, ok := languages[slug]
+	if !ok {
+		return ""
+	}
+	current, ok := rl.byID[currentID]
+	if !ok {
+		return ""
+	}
+	prev := ""
+	for _, l := range rl.published {
+		if l.Order >= current.Order {
+			break
+		}
+		prev = l.ID
+	}
+	return prev
+}
+
+// Word is a vocab item together with the lesson that first introduces it.
+type Word struct {
+	VocabItem
+	LessonID    string
+	LessonTitle string
+	LessonOrder int
+}
+
+// GetWords returns every vocab item in a language's published lessons, in
+// lesson order. A word ID that appears in several lessons is listed once,
+// under its first lesson.
+func GetWords(slug string) []Word {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	rl, ok := languages[slug]
+	if !ok {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var words []Word
+	for _, l := range rl.published {
+		for _, section := range l.Sections {
+			for _, item := range section.Items {
+				if seen[item.ID] {
+					continue
+				}
+				seen[item.ID] = true
+				words = append(words, Word{
+					VocabItem:   item,
+					LessonID:    l.ID,
+					LessonTitle: l.Title,
+					LessonOrder: l.Order,
+				})
+			}
+		}
+	}
+	return words
+}
@@ -0,0 +1,117 @@
+package lessons
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+//go:generate go run ../../cmd/lessonschema -out lesson.schema.json
+
+// Schema returns a JSON Schema (draft 2020-12) for lesson files, generated
+// from the Lesson type and the section and question type rules, so it
+// accepts exactly what LoadFromFS does short of the checks in validateLesson.
+// lesson.schema.json in this directory is its output; regenerate it with
+// go generate after changing the types.
+func Schema() ([]byte, error) {
+	defs := make(map[string]any)
+	root := schemaFor(reflect.TypeFor[Lesson](), defs)
+	doc := map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "SpeakEasy lesson",
+		"$ref":    root["$ref"],
+		"$defs":   defs,
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// schemaFor returns the schema for t, adding struct definitions to defs.
+func schemaFor(t reflect.Type, defs map[string]any) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return map[string]any{"anyOf": []any{schemaFor(t.Elem(), defs), map[string]any{"type": "null"}}}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem(), defs)}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Int:
+		return map[string]any{"type": "integer"}
+	case reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Struct:
+		ref := map[string]any{"$ref": "#/$defs/" + t.Name()}
+		if _, done := defs[t.Name()]; !done {
+			defs[t.Name()] = nil // placeholder while the fields are walked
+			defs[t.Name()] = structSchema(t, defs)
+		}
+		return ref
+	}
+	return map[string]any{}
+}
+
+// structSchema describes a struct as a closed object. Section and question
+// types add an if/then per type value for the fields that type requires and
+// the other types' fields it must not have.
+func structSchema(t reflect.Type, defs map[string]any) map[string]any {
+	vs, hasVariants := variants[t]
+	var governed map[string]bool
+	if hasVariants {
+		governed = variantFields(vs.types)
+	}
+
+	props := make(map[string]any)
+	required := []string{}
+	for _, f := range jsonFields(t) {
+		props[f.name] = schemaFor(f.typ, defs)
+		if f.required && !governed[f.name] {
+			required = append(required, f.name)
+		}
+	}
+	s := map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+	if !hasVariants {
+		return s
+	}
+
+	names := sortedTypes(vs.types)
+	props["type"] = map[string]any{"enum": names}
+	var rules []any
+	for _, name := range names {
+		v := vs.types[name]
+		uses := make(map[string]bool)
+		for _, f := range v.required {
+			uses[f] = true
+		}
+		for _, f := range v.optional {
+			uses[f] = true
+		}
+		forbidden := make(map[string]any)
+		for f := range governed {
+			if !uses[f] {
+				forbidden[f] = false
+			}
+		}
+		then := map[string]any{"required": v.required}
+		if len(forbidden) > 0 {
+			then["properties"] = forbidden
+		}
+		rules = append(rules, map[string]any{
+			"if": map[string]any{
+				"properties": map[string]any{"type": map[string]any{"const": name}},
+				"required":   []string{"type"},
+			},
+			"then": then,
+		})
+	}
+	s["allOf"] = rules
+	return s
+}
@@ -0,0 +1,101 @@
+package lessons
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Source is the directory a language's lesson files were read from, when the
+// lesson editor can write back to it: a language pack directory, or a
+// built-in language in development mode. Embedded lessons and zipped packs
+// have no Source.
+type Source struct {
+	Dir string // holds data/
+
+	// Reload reads the files again and registers the result. It leaves the
+	// registered lessons alone if they fail to load.
+	Reload func() error
+}
+
+var (
+	sourcesMu sync.RWMutex
+	sources   = make(map[string]Source)
+)
+
+// SetSource records where a language's lessons can be saved.
+func SetSource(slug string, src Source) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	sources[slug] = src
+}
+
+// GetSource returns where a language's lessons can be saved; ok is false if
+// they are read-only.
+func GetSource(slug string) (src Source, ok bool) {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+	src, ok = sources[slug]
+	return src, ok
+}
+
+// SaveLesson writes a lesson file to the language's Source and reloads the
+// language. If the language no longer loads, for example because the lesson
+// clashes with another, the file is put back as it was and the error
+// returned.
+func SaveLesson(slug, file string, data []byte) error {
+	src, ok := GetSource(slug)
+	if !ok {
+		return fmt.Errorf("language %q is read-only", slug)
+	}
+	if file != filepath.Base(file) || filepath.Ext(file) != ".json" {
+		return fmt.Errorf("invalid lesson file name %q", file)
+	}
+	path := filepath.Join(src.Dir, "data", file)
+
+	old, err := os.ReadFile(path)
+	existed := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err := writeFileAtomic(path, data); err != nil {
+		return err
+	}
+	reloadErr := src.Reload()
+	if reloadErr == nil {
+		return nil
+	}
+
+	if existed {
+		err = writeFileAtomic(path, old)
+	} else {
+		err = os.Remove(path)
+	}
+	if err != nil {
+		return errors.Join(reloadErr, fmt.Errorf("restore %s: %w", path, err))
+	}
+	return reloadErr
+}
+
+// writeFileAtomic replaces path with data so a reader, such as the
+// development mode watcher, never sees a half-written file.
+func writeFileAtomic(path string, data []byte) error {
+	// Not named *.json, so it is never loaded as a lesson
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
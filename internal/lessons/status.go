@@ -0,0 +1,80 @@
+package lessons
+
+// Status is a user's progress state for one lesson. Progress only ever moves
+// forward: locked → available → in_progress → completed.
+type Status string
+
+const (
+	StatusLocked     Status = "locked"
+	StatusAvailable  Status = "available"
+	StatusInProgress Status = "in_progress"
+	StatusCompleted  Status = "completed"
+)
+
+// Event is something a user does that may move a lesson's status.
+type Event int
+
+const (
+	EventUnlock     Event = iota // the previous lesson was passed
+	EventView                    // the lesson page was opened
+	EventQuizFailed              // the quiz was submitted below the pass mark
+	EventQuizPassed              // the quiz was submitted at or above the pass mark
+)
+
+// transitions lists the allowed moves out of each status. Anything not listed
+// leaves the status unchanged.
+var transitions = map[Status]map[Event]Status{
+	StatusLocked: {
+		EventUnlock: StatusAvailable,
+	},
+	StatusAvailable: {
+		EventView:       StatusInProgress,
+		EventQuizFailed: StatusInProgress,
+		EventQuizPassed: StatusCompleted,
+	},
+	StatusInProgress: {
+		EventQuizPassed: StatusCompleted,
+	},
+}
+
+// Rank orders statuses so that a later status always has a higher rank.
+// Unknown statuses rank below locked. Progress rows store it as status_rank
+// next to the status, and the upserts compare it so concurrent writes can
+// never move a lesson backwards. This is the only place statuses are ranked,
+// though reordering them needs a migration to re-rank the stored rows.
+func (s Status) Rank() int {
+	switch s {
+	case StatusLocked:
+		return 0
+	case StatusAvailable:
+		return 1
+	case StatusInProgress:
+		return 2
+	case StatusCompleted:
+		return 3
+	}
+	return -1
+}
+
+// Next returns the status after event e. Events that are not allowed from s
+// (viewing a locked lesson, failing a completed quiz) leave s unchanged.
+func (s Status) Next(e Event) Status {
+	if to, ok := transitions[s][e]; ok {
+		return to
+	}
+	return s
+}
+
+// Accessible reports whether the lesson can be opened and its quiz taken.
+func (s Status) Accessible() bool {
+	return s.Rank() > StatusLocked.Rank()
+}
+
+// InitialStatus is the status of a lesson with no progress row yet: the
+// first lesson of a language is open, everything else starts locked.
+func InitialStatus(l *Lesson) Status {
+	if l.Order == 1 {
+		return StatusAvailable
+	}
+	return StatusLocked
+}
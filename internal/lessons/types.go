@@ -1,15 +1,21 @@
 package lessons
 
-// Language describes a language available in SpeakEasy.
+// Language describes a language available in SpeakEasy. The JSON form is
+// used by language packs' language.json.
 type Language struct {
-	Slug        string // URL-safe identifier, e.g. "serbian"
-	DisplayName string // Human-readable name, e.g. "Serbian"
-	TTSCode     string // BCP-47 language code for TTS, e.g. "sr"
-	HasDualScript bool   // true if the language has an alternate script (e.g. Cyrillic)
-	ScriptLabel   string // label for the primary script, e.g. "Latin"
-	AltScriptLabel string // label for the alternate script, e.g. "Cyrillic"
+	Slug           string `json:"slug"`             // URL-safe identifier, e.g. "serbian"
+	DisplayName    string `json:"display_name"`     // Human-readable name, e.g. "Serbian"
+	TTSCode        string `json:"tts_code"`         // BCP-47 language code for TTS, e.g. "sr"
+	HasDualScript  bool   `json:"has_dual_script"`  // true if the language has an alternate script (e.g. Cyrillic)
+	ScriptLabel    string `json:"script_label"`     // label for the primary script, e.g. "Latin"
+	AltScriptLabel string `json:"alt_script_label"` // label for the alternate script, e.g. "Cyrillic"
+	PassScore      int    `json:"pass_score"`       // default quiz pass mark in percent; 0 means DefaultPassScore
 }
 
+// DefaultPassScore is the quiz pass mark, in percent, for languages that do
+// not set their own.
+const DefaultPassScore = 70
+
 // Lesson represents a single lesson in any language.
 type Lesson struct {
 	ID           string    `json:"id"`
@@ -20,6 +26,35 @@ type Lesson struct {
 	Illustration string    `json:"illustration"`
 	Sections     []Section `json:"sections"`
 	Quiz         Quiz      `json:"quiz"`
+
+	// PassScore overrides the language's pass mark for this lesson's quiz.
+	// After registration it always holds the effective pass mark.
+	PassScore int `json:"pass_score,omitempty"`
+
+	// Draft lessons are shown only to admins, so a lesson can be written and
+	// tried out on the live site before learners see it.
+	Draft bool `json:"draft,omitempty"`
+
+	// Checkpoint makes the lesson a cumulative test of the lessons before
+	// it rather than a lesson of its own. Like any lesson, it has to be
+	// passed to unlock the next one, so it gates the next unit.
+	Checkpoint *Checkpoint `json:"checkpoint,omitempty"`
+
+	// Schema is the optional "$schema" key, which points editors at
+	// lesson.schema.json for completion and checking.
+	Schema string `json:"$schema,omitempty"`
+
+	// Version identifies the lesson's content: a hash of its canonical
+	// encoding, set when it is loaded. Quiz attempts record it, so results
+	// given against an earlier version of the questions can be told apart.
+	Version string `json:"-"`
+
+	// File is the name of the file under data/ the lesson was loaded from.
+	File string `json:"-"`
+
+	// IllustrationURL is where Illustration is served from: /static/svg/
+	// for built-in lessons, the pack's svg/ directory for language packs.
+	IllustrationURL string `json:"-"`
 }
 
 type Section struct {
@@ -29,6 +64,47 @@ type Section struct {
 	Explanation string      `json:"explanation,omitempty"`
 	Examples    []Example   `json:"examples,omitempty"`
 	Content     string      `json:"content,omitempty"`
+
+	// Blocks hold a grammar section's structured material, shown after its
+	// explanation and examples.
+	Blocks []GrammarBlock `json:"blocks,omitempty"`
+
+	// Speakers and Lines hold a dialogue section's conversation.
+	Speakers []Speaker      `json:"speakers,omitempty"`
+	Lines    []DialogueLine `json:"lines,omitempty"`
+}
+
+// Speaker is someone taking part in a dialogue.
+type Speaker struct {
+	ID    string `json:"id"`              // referred to by the lines, e.g. "ana"
+	Name  string `json:"name"`            // shown beside their lines
+	Voice string `json:"voice,omitempty"` // "female" or "male"
+}
+
+// DialogueLine is one thing said in a dialogue.
+type DialogueLine struct {
+	Speaker       string `json:"speaker"` // a Speaker's ID
+	TargetPrimary string `json:"target_primary"`
+	TargetAlt     string `json:"target_alt,omitempty"`
+	English       string `json:"english"`
+}
+
+// Voices are the TTS voices a dialogue speaker can have.
+var Voices = []string{"female", "male"}
+
+// Speaker returns the dialogue speaker with the given ID, with its voice
+// filled in: speakers without one take turns at the female and male voices
+// in the order they are listed, so each sounds different from the last.
+func (s Section) Speaker(id string) Speaker {
+	for i, sp := range s.Speakers {
+		if sp.ID == id {
+			if sp.Voice == "" {
+				sp.Voice = Voices[i%len(Voices)]
+			}
+			return sp
+		}
+	}
+	return Speaker{ID: id, Name: id, Voice: Voices[0]}
 }
 
 type VocabItem struct {
@@ -53,8 +129,100 @@ type Example struct {
 	TargetAlt     string `json:"target_alt,omitempty"`
 }
 
+// GrammarBlock is a structured part of a grammar section: a table such as a
+// case table, a conjugation or declension paradigm, or words split into
+// their morphemes. Every piece of target-language text in it has an
+// alternate-script form and can be played.
+type GrammarBlock struct {
+	Type  string `json:"type"`
+	Title string `json:"title,omitempty"`
+
+	// A table has a heading per column, the first over the row labels, and
+	// a target-language cell per column after it in each row.
+	Headers []string   `json:"headers,omitempty"`
+	Rows    []TableRow `json:"rows,omitempty"`
+
+	// A paradigm lists the forms of one word, optionally under its
+	// dictionary form.
+	Lemma *TargetText `json:"lemma,omitempty"`
+	Forms []Form      `json:"forms,omitempty"`
+
+	// A morphemes block shows words built from parts, some highlighted.
+	Words []MorphemeWord `json:"words,omitempty"`
+}
+
+// TargetText is a piece of target-language text.
+type TargetText struct {
+	TargetPrimary string `json:"target_primary"`
+	TargetAlt     string `json:"target_alt,omitempty"`
+}
+
+type TableRow struct {
+	Label string       `json:"label"` // e.g. "Genitive"
+	Cells []TargetText `json:"cells"` // empty text for no form
+}
+
+// PrimaryCells returns the row's cells in the primary script.
+func (r TableRow) PrimaryCells() []string {
+	cells := make([]string, len(r.Cells))
+	for i, c := range r.Cells {
+		cells[i] = c.TargetPrimary
+	}
+	return cells
+}
+
+// AltCells returns the row's cells in the alternate script, or nil when
+// none of them has one.
+func (r TableRow) AltCells() []string {
+	var cells []string
+	for i, c := range r.Cells {
+		if c.TargetAlt != "" && cells == nil {
+			cells = make([]string, len(r.Cells))
+		}
+		if cells != nil {
+			cells[i] = c.TargetAlt
+		}
+	}
+	return cells
+}
+
+// Form is one form in a paradigm, e.g. "ja" → "čitam" ("I read").
+type Form struct {
+	Label         string `json:"label"`
+	TargetPrimary string `json:"target_primary"`
+	TargetAlt     string `json:"target_alt,omitempty"`
+	English       string `json:"english,omitempty"`
+}
+
+// MorphemeWord is a word and the parts it is built from, e.g. "membaca"
+// from "mem-" and "baca". The parts needn't spell the word exactly, since
+// affixes can change the sounds they meet.
+type MorphemeWord struct {
+	TargetPrimary string     `json:"target_primary"`
+	TargetAlt     string     `json:"target_alt,omitempty"`
+	English       string     `json:"english,omitempty"`
+	Parts         []Morpheme `json:"parts"`
+}
+
+type Morpheme struct {
+	TargetPrimary string `json:"target_primary"`
+	TargetAlt     string `json:"target_alt,omitempty"`
+	Gloss         string `json:"gloss,omitempty"`     // e.g. "active verb prefix"
+	Highlight     bool   `json:"highlight,omitempty"` // the part the block is about
+}
+
 type Quiz struct {
 	Questions []Question `json:"questions"`
+
+	// Generate opts the lesson in to questions built from its vocabulary,
+	// asked after the hand-written ones and different on every attempt.
+	Generate *Generate `json:"generate,omitempty"`
+}
+
+// Generate configures a quiz's generated questions.
+type Generate struct {
+	Count int      `json:"count"`           // questions per attempt
+	Types []string `json:"types,omitempty"` // question types to generate; empty means DefaultGeneratedTypes
 }
 
 type Question struct {
@@ -66,13 +234,32 @@ type Question struct {
 	CorrectAnswers []string `json:"correct_answers,omitempty"`
 	Pairs          []Pair   `json:"pairs,omitempty"`
 	WordID         string   `json:"word_id,omitempty"`
+	Weight         float64  `json:"weight,omitempty"` // points the question is worth; 0 means 1
 
 	// Computed fields for template rendering
 	ShuffledTarget []string `json:"-"`
 	AudioText      string   `json:"-"`
+
+	// TrackedWordID is the vocab item whose mastery this question updates:
+	// WordID when set, otherwise found by looking up a type_answer's correct
+	// answers in the lesson vocab. Empty if the question maps to no word.
+	TrackedWordID string `json:"-"`
+}
+
+// Points returns how much the question counts towards the quiz score.
+func (q Question) Points() float64 {
+	if q.Weight > 0 {
+		return q.Weight
+	}
+	return 1
 }
 
 type Pair struct {
 	English string `json:"english"`
 	Target  string `json:"target"`
+	WordID  string `json:"word_id,omitempty"`
+
+	// TrackedWordID is WordID when set, otherwise the lesson vocab item
+	// whose target (or, failing that, English) text matches the pair.
+	TrackedWordID string `json:"-"`
 }
@@ -0,0 +1,606 @@
+package lessons
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lesson files are checked in two passes. The first walks the raw JSON
+// against the Lesson type, so a misspelled key, a value of the wrong type or
+// a field the section or question type doesn't use is reported with its JSON
+// path instead of being silently ignored. The second, validateLesson, checks
+// the decoded lesson for mistakes the shape can't show, such as a correct
+// index past the last option.
+
+// FieldError is a problem at one place in a lesson file.
+type FieldError struct {
+	File    string // e.g. "serbian/lesson01.json"
+	Path    string // JSON path, e.g. "$.quiz.questions[2].correct"
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	if e.File == "" {
+		return e.Path + ": " + e.Message
+	}
+	return e.File + ": " + e.Path + ": " + e.Message
+}
+
+// Errors is every problem found while loading a language's lessons.
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	lines := make([]string, len(e))
+	for i, fe := range e {
+		lines[i] = fe.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap lets errors.As find the individual FieldErrors.
+func (e Errors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, fe := range e {
+		errs[i] = fe
+	}
+	return errs
+}
+
+// Split returns the individual problems in err: the FieldErrors of an
+// Errors, or err itself.
+func Split(err error) []error {
+	var errs Errors
+	if errors.As(err, &errs) {
+		return errs.Unwrap()
+	}
+	return []error{err}
+}
+
+// variant is the fields one value of a "type" discriminator uses, besides
+// the fields every value has.
+type variant struct {
+	required []string
+	optional []string
+}
+
+// variants lists the section and question types and their fields. Fields of
+// the other types are not allowed, so a vocab section with "content" is an
+// error rather than text that never shows up.
+var variants = map[reflect.Type]struct {
+	noun  string
+	types map[string]variant
+}{
+	reflect.TypeFor[Section](): {"section", map[string]variant{
+		"vocab":         {required: []string{"items"}},
+		"grammar":       {required: []string{"explanation"}, optional: []string{"examples", "blocks"}},
+		"cultural_note": {required: []string{"content"}},
+		"dialogue":      {required: []string{"speakers", "lines"}},
+	}},
+	reflect.TypeFor[GrammarBlock](): {"grammar block", map[string]variant{
+		"table":     {required: []string{"headers", "rows"}},
+		"paradigm":  {required: []string{"forms"}, optional: []string{"lemma"}},
+		"morphemes": {required: []string{"words"}},
+	}},
+	reflect.TypeFor[Question](): {"question", map[string]variant{
+		"multiple_choice":   {required: []string{"question", "options", "correct"}, optional: []string{"word_id", "weight"}},
+		"listen_and_choose": {required: []string{"options", "correct"}, optional: []string{"word_id", "weight"}},
+		"type_answer":       {required: []string{"prompt", "correct_answers"}, optional: []string{"word_id", "weight"}},
+		"match_pairs":       {required: []string{"pairs"}, optional: []string{"weight"}},
+	}},
+}
+
+// SectionTypes returns the section types a lesson can use, in order.
+func SectionTypes() []string {
+	return sortedTypes(variants[reflect.TypeFor[Section]()].types)
+}
+
+// GrammarBlockTypes returns the grammar block types, in order.
+func GrammarBlockTypes() []string {
+	return sortedTypes(variants[reflect.TypeFor[GrammarBlock]()].types)
+}
+
+// QuestionTypes returns the quiz question types, in order.
+func QuestionTypes() []string {
+	return sortedTypes(variants[reflect.TypeFor[Question]()].types)
+}
+
+// field is a JSON field of a struct.
+type field struct {
+	name      string
+	index     int
+	typ       reflect.Type
+	required  bool // neither omitempty nor a pointer
+	omitempty bool
+}
+
+// jsonFields returns the JSON fields of struct type t in declaration order.
+func jsonFields(t reflect.Type) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if !sf.IsExported() || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, field{
+			name:      name,
+			index:     i,
+			typ:       sf.Type,
+			required:  opts != "omitempty" && sf.Type.Kind() != reflect.Pointer,
+			omitempty: opts == "omitempty",
+		})
+	}
+	return fields
+}
+
+// variantFields returns every field named by any of the types in a variant
+// set. Their presence depends on the type; the remaining fields are common.
+func variantFields(types map[string]variant) map[string]bool {
+	names := make(map[string]bool)
+	for _, v := range types {
+		for _, f := range v.required {
+			names[f] = true
+		}
+		for _, f := range v.optional {
+			names[f] = true
+		}
+	}
+	return names
+}
+
+// sortedTypes returns the type names of a variant set in order.
+func sortedTypes(types map[string]variant) []string {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse decodes and validates one lesson file, reporting every problem as
+// LoadFromFS would, except for clashes with the language's other lessons.
+// file names the file in the errors. The derived fields LoadFromFS fills in
+// are left empty.
+func Parse(file string, data []byte) (*Lesson, error) {
+	lesson, errs := parseLesson(file, data)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return lesson, nil
+}
+
+// parseLesson decodes and validates a lesson file.
+func parseLesson(file string, data []byte) (*Lesson, Errors) {
+	lesson, errs := decodeLesson(file, data)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	errs = validateLesson(lesson)
+	for _, e := range errs {
+		e.File = file
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return lesson, nil
+}
+
+// decodeLesson strictly decodes a lesson file. A lesson is returned only if
+// there are no errors.
+func decodeLesson(file string, data []byte) (*Lesson, Errors) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw any
+	if err := dec.Decode(&raw); err != nil {
+		return nil, Errors{syntaxError(file, data, err)}
+	}
+	if dec.More() {
+		return nil, Errors{{File: file, Path: "$", Message: "unexpected data after the lesson object"}}
+	}
+
+	c := checker{file: file}
+	c.check(raw, reflect.TypeFor[Lesson](), "$")
+	if len(c.errs) > 0 {
+		return nil, c.errs
+	}
+
+	// The check above reports everything the standard decoder would reject,
+	// with better paths, so this only fails if the two disagree.
+	var lesson Lesson
+	strict := json.NewDecoder(bytes.NewReader(data))
+	strict.DisallowUnknownFields()
+	if err := strict.Decode(&lesson); err != nil {
+		return nil, Errors{{File: file, Path: "$", Message: err.Error()}}
+	}
+	return &lesson, nil
+}
+
+// syntaxError reports malformed JSON with its line and column.
+func syntaxError(file string, data []byte, err error) *FieldError {
+	var se *json.SyntaxError
+	if errors.As(err, &se) {
+		before := data[:min(int(se.Offset), len(data))]
+		line := bytes.Count(before, []byte("\n")) + 1
+		col := len(before) - bytes.LastIndexByte(before, '\n')
+		return &FieldError{File: file, Path: "$", Message: fmt.Sprintf("invalid JSON at line %d, column %d: %v", line, col, se)}
+	}
+	return &FieldError{File: file, Path: "$", Message: "invalid JSON: " + err.Error()}
+}
+
+// checker walks decoded JSON against a Go type, collecting errors.
+type checker struct {
+	file string
+	errs Errors
+}
+
+func (c *checker) addf(path, format string, args ...any) {
+	c.errs = append(c.errs, &FieldError{File: c.file, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) check(v any, t reflect.Type, path string) {
+	if t.Kind() == reflect.Pointer {
+		if v == nil {
+			return
+		}
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			c.addf(path, "want an object, got %s", jsonKind(v))
+			return
+		}
+		c.checkObject(obj, t, path)
+
+	case reflect.Slice:
+		arr, ok := v.([]any)
+		if !ok {
+			c.addf(path, "want an array, got %s", jsonKind(v))
+			return
+		}
+		for i, elem := range arr {
+			c.check(elem, t.Elem(), path+"["+strconv.Itoa(i)+"]")
+		}
+
+	case reflect.String:
+		if _, ok := v.(string); !ok {
+			c.addf(path, "want a string, got %s", jsonKind(v))
+		}
+
+	case reflect.Int:
+		n, ok := v.(json.Number)
+		if !ok {
+			c.addf(path, "want a whole number, got %s", jsonKind(v))
+		} else if _, err := n.Int64(); err != nil {
+			c.addf(path, "want a whole number, got %s", n)
+		}
+
+	case reflect.Float64:
+		if _, ok := v.(json.Number); !ok {
+			c.addf(path, "want a number, got %s", jsonKind(v))
+		}
+
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			c.addf(path, "want true or false, got %s", jsonKind(v))
+		}
+	}
+}
+
+func (c *checker) checkObject(obj map[string]any, t reflect.Type, path string) {
+	fields := jsonFields(t)
+	byName := make(map[string]field, len(fields))
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		byName[f.name] = f
+		names[i] = f.name
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	vs, hasVariants := variants[t]
+	var governed map[string]bool
+	if hasVariants {
+		governed = variantFields(vs.types)
+	}
+
+	suggested := make(map[string]bool) // not reported missing as well
+	for _, k := range keys {
+		f, ok := byName[k]
+		if !ok {
+			if s := suggest(k, names); s != "" {
+				c.addf(path, "unknown field %q (did you mean %q?)", k, s)
+				suggested[s] = true
+			} else {
+				c.addf(path, "unknown field %q", k)
+			}
+			continue
+		}
+		c.check(obj[k], f.typ, path+"."+k)
+	}
+	for _, f := range fields {
+		if _, ok := obj[f.name]; !ok && f.required && !governed[f.name] && !suggested[f.name] {
+			c.addf(path, "missing field %q", f.name)
+		}
+	}
+
+	if !hasVariants {
+		return
+	}
+	typ, ok := obj["type"].(string)
+	if !ok {
+		return // already reported as missing or not a string
+	}
+	v, ok := vs.types[typ]
+	if !ok {
+		c.addf(path+".type", "unknown %s type %q; want one of %s", vs.noun, typ, strings.Join(sortedTypes(vs.types), ", "))
+		return
+	}
+	uses := make(map[string]bool)
+	for _, name := range v.required {
+		uses[name] = true
+		if _, ok := obj[name]; !ok && !suggested[name] {
+			c.addf(path, "missing field %q, required in a %s %s", name, typ, vs.noun)
+		}
+	}
+	for _, name := range v.optional {
+		uses[name] = true
+	}
+	for _, k := range keys {
+		if governed[k] && !uses[k] {
+			c.addf(path+"."+k, "not used in a %s %s", typ, vs.noun)
+		}
+	}
+}
+
+// jsonKind describes a decoded JSON value for error messages.
+func jsonKind(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case json.Number:
+		return "a number"
+	case bool:
+		return "a boolean"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// suggest returns the field name closest to an unknown key, if it is close
+// enough to be a likely typo.
+func suggest(key string, names []string) string {
+	best, bestDist := "", 3
+	for _, name := range names {
+		if d := editDistance(key, name); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// validateLesson catches mistakes that would otherwise surface as a broken
+// page or an unanswerable question. Paths are relative to the lesson.
+func validateLesson(l *Lesson) Errors {
+	var errs Errors
+	addf := func(path, format string, args ...any) {
+		errs = append(errs, &FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if l.ID == "" {
+		addf("$.id", "lesson has no id")
+	}
+
+	words := make(map[string]bool)
+	for i, s := range l.Sections {
+		path := fmt.Sprintf("$.sections[%d]", i)
+		switch s.Type {
+		case "vocab":
+			if len(s.Items) == 0 {
+				addf(path+".items", "a vocab section needs items")
+			}
+			for j, item := range s.Items {
+				switch {
+				case item.ID == "":
+					addf(fmt.Sprintf("%s.items[%d].id", path, j), "vocab item has no id")
+				case words[item.ID]:
+					addf(fmt.Sprintf("%s.items[%d].id", path, j), "duplicate vocab item id %q", item.ID)
+				}
+				words[item.ID] = true
+			}
+		case "grammar":
+			if s.Explanation == "" {
+				addf(path+".explanation", "a grammar section needs an explanation")
+			}
+			for j, b := range s.Blocks {
+				validateGrammarBlock(b, fmt.Sprintf("%s.blocks[%d]", path, j), addf)
+			}
+		case "cultural_note":
+			if s.Content == "" {
+				addf(path+".content", "a cultural_note section needs content")
+			}
+		case "dialogue":
+			validateDialogue(s, path, addf)
+		default:
+			addf(path+".type", "unknown section type %q", s.Type)
+		}
+	}
+
+	for i, q := range l.Quiz.Questions {
+		path := fmt.Sprintf("$.quiz.questions[%d]", i)
+		switch q.Type {
+		case "multiple_choice", "listen_and_choose":
+			if q.Correct < 0 || q.Correct >= len(q.Options) {
+				addf(path+".correct", "correct index %d is outside its %d options", q.Correct, len(q.Options))
+			}
+		case "type_answer":
+			if len(q.CorrectAnswers) == 0 {
+				addf(path+".correct_answers", "type_answer needs correct_answers")
+			}
+		case "match_pairs":
+			if len(q.Pairs) == 0 {
+				addf(path+".pairs", "match_pairs needs pairs")
+			}
+			for j, p := range q.Pairs {
+				if p.WordID != "" && !words[p.WordID] {
+					addf(fmt.Sprintf("%s.pairs[%d].word_id", path, j), "no vocab item %q in this lesson", p.WordID)
+				}
+			}
+		default:
+			addf(path+".type", "unknown question type %q", q.Type)
+		}
+		if q.WordID != "" && !words[q.WordID] {
+			addf(path+".word_id", "no vocab item %q in this lesson", q.WordID)
+		}
+	}
+
+	if g := l.Quiz.Generate; g != nil {
+		if g.Count < 1 {
+			addf("$.quiz.generate.count", "count must be at least 1")
+		}
+		// A checkpoint generates from the lessons it covers
+		if len(words) == 0 && l.Checkpoint == nil {
+			addf("$.quiz.generate", "generated questions need a vocab section to build them from")
+		}
+		for i, typ := range g.Types {
+			if !slices.Contains(GeneratedTypes(), typ) {
+				addf(fmt.Sprintf("$.quiz.generate.types[%d]", i), "%q questions can't be generated; use one of %s", typ, strings.Join(GeneratedTypes(), ", "))
+			}
+		}
+	}
+
+	if c := l.Checkpoint; c != nil {
+		switch {
+		case c.Sample < 0:
+			addf("$.checkpoint.sample", "sample can't be negative")
+		case c.Sample == 0 && l.Quiz.Generate == nil && len(l.Quiz.Questions) == 0:
+			addf("$.checkpoint", "a checkpoint needs questions: sample some from earlier lessons, generate them or write its own")
+		}
+	}
+	return errs
+}
+
+// validateGrammarBlock checks that a grammar block has something to show and
+// that a table's rows fit its columns.
+func validateGrammarBlock(b GrammarBlock, path string, addf func(path, format string, args ...any)) {
+	switch b.Type {
+	case "table":
+		if len(b.Headers) < 2 {
+			addf(path+".headers", "a table needs a heading for its row labels and at least one column")
+		}
+		if len(b.Rows) == 0 {
+			addf(path+".rows", "a table needs rows")
+		}
+		for i, row := range b.Rows {
+			if len(b.Headers) >= 2 && len(row.Cells) != len(b.Headers)-1 {
+				addf(fmt.Sprintf("%s.rows[%d].cells", path, i), "row has %d cells for the table's %d columns", len(row.Cells), len(b.Headers)-1)
+			}
+		}
+	case "paradigm":
+		if len(b.Forms) == 0 {
+			addf(path+".forms", "a paradigm needs forms")
+		}
+		for i, form := range b.Forms {
+			if form.TargetPrimary == "" {
+				addf(fmt.Sprintf("%s.forms[%d].target_primary", path, i), "form has no text")
+			}
+		}
+	case "morphemes":
+		if len(b.Words) == 0 {
+			addf(path+".words", "a morphemes block needs words")
+		}
+		for i, w := range b.Words {
+			wp := fmt.Sprintf("%s.words[%d]", path, i)
+			if w.TargetPrimary == "" {
+				addf(wp+".target_primary", "word has no text")
+			}
+			if len(w.Parts) == 0 {
+				addf(wp+".parts", "word needs its parts")
+			}
+		}
+	default:
+		addf(path+".type", "unknown grammar block type %q", b.Type)
+	}
+}
+
+// validateDialogue checks that a dialogue has speakers with unique IDs and
+// known voices, and lines said by them.
+func validateDialogue(s Section, path string, addf func(path, format string, args ...any)) {
+	if len(s.Speakers) == 0 {
+		addf(path+".speakers", "a dialogue needs speakers")
+	}
+	speakers := make(map[string]bool)
+	for i, sp := range s.Speakers {
+		sPath := fmt.Sprintf("%s.speakers[%d]", path, i)
+		switch {
+		case sp.ID == "":
+			addf(sPath+".id", "speaker has no id")
+		case speakers[sp.ID]:
+			addf(sPath+".id", "duplicate speaker id %q", sp.ID)
+		}
+		speakers[sp.ID] = true
+		if sp.Name == "" {
+			addf(sPath+".name", "speaker has no name")
+		}
+		if sp.Voice != "" && !slices.Contains(Voices, sp.Voice) {
+			addf(sPath+".voice", "unknown voice %q (want one of %s)", sp.Voice, strings.Join(Voices, ", "))
+		}
+	}
+
+	if len(s.Lines) == 0 {
+		addf(path+".lines", "a dialogue needs lines")
+	}
+	for i, line := range s.Lines {
+		lPath := fmt.Sprintf("%s.lines[%d]", path, i)
+		if !speakers[line.Speaker] {
+			addf(lPath+".speaker", "line is said by %q, who is not one of the speakers", line.Speaker)
+		}
+		if line.TargetPrimary == "" {
+			addf(lPath+".target_primary", "line has no text")
+		}
+	}
+}
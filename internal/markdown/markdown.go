@@ -0,0 +1,275 @@
+// Package markdown renders the small Markdown subset lesson prose may use:
+// paragraphs, **bold**, *italics*, bulleted and numbered lists, links and
+// [[target-language]] spans that play their audio when clicked. Everything
+// else, HTML included, is shown as the text it is, so the output is safe to
+// put in a page as it stands.
+package markdown
+
+import (
+	"html/template"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Target describes the language of a text's [[...]] spans.
+type Target struct {
+	TTSCode    string // language code the audio is spoken in, e.g. "sr"
+	DualScript bool   // spans are also shown in the alternate script
+	// ToAlt converts a span to the alternate script when it doesn't give one
+	// itself, as in [[kafa|кафа]]; nil to show the span unchanged.
+	ToAlt func(string) string
+}
+
+// Render renders src as HTML.
+func Render(src string, target Target) template.HTML {
+	r := renderer{target: target}
+	r.blocks(strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n"))
+	return template.HTML(r.sb.String())
+}
+
+type renderer struct {
+	sb     strings.Builder
+	target Target
+}
+
+// blocks renders lines as paragraphs and lists, which are separated by blank
+// lines or start at a list marker.
+func (r *renderer) blocks(lines []string) {
+	var para []string
+	var list string // "ul" or "ol" while in a list
+	var item []string
+
+	flushPara := func() {
+		if len(para) > 0 {
+			r.sb.WriteString("<p>")
+			r.inline(strings.Join(para, " "))
+			r.sb.WriteString("</p>\n")
+			para = nil
+		}
+	}
+	flushItem := func() {
+		if len(item) > 0 {
+			r.sb.WriteString("<li>")
+			r.inline(strings.Join(item, " "))
+			r.sb.WriteString("</li>\n")
+			item = nil
+		}
+	}
+	endList := func() {
+		flushItem()
+		if list != "" {
+			r.sb.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			flushPara()
+			endList()
+			continue
+		}
+		if kind, text, ok := listItem(trimmed); ok {
+			flushPara()
+			if kind != list {
+				endList()
+				r.sb.WriteString("<" + kind + ">\n")
+				list = kind
+			}
+			flushItem()
+			item = []string{text}
+			continue
+		}
+		// An indented line carries on the item above it
+		if list != "" && line != strings.TrimLeft(line, " \t") {
+			item = append(item, trimmed)
+			continue
+		}
+		endList()
+		para = append(para, trimmed)
+	}
+	flushPara()
+	endList()
+}
+
+// listItem reports whether line starts a list item: "- ", "* " or "+ " for a
+// bulleted list, "1. " or "1) " for a numbered one.
+func listItem(line string) (kind, text string, ok bool) {
+	if len(line) > 2 && strings.ContainsRune("-*+", rune(line[0])) && line[1] == ' ' {
+		return "ul", strings.TrimSpace(line[2:]), true
+	}
+	digits := len(line) - len(strings.TrimLeft(line, "0123456789"))
+	if digits > 0 && digits < 10 && len(line) > digits+2 &&
+		(line[digits] == '.' || line[digits] == ')') && line[digits+1] == ' ' {
+		return "ol", strings.TrimSpace(line[digits+2:]), true
+	}
+	return "", "", false
+}
+
+// inline renders the emphasis, links and target-language spans in s,
+// escaping everything else. Markers that aren't closed are shown as text.
+func (r *renderer) inline(s string) {
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && strings.ContainsRune(`\*_[]()`, rune(s[i+1])):
+			r.text(s[i+1 : i+2])
+			i += 2
+			continue
+
+		case strings.HasPrefix(s[i:], "[["):
+			if end := strings.Index(s[i+2:], "]]"); end > 0 {
+				r.speakable(s[i+2 : i+2+end])
+				i += end + 4
+				continue
+			}
+
+		case s[i] == '[':
+			if text, href, n, ok := link(s[i:]); ok {
+				r.sb.WriteString(`<a href="` + template.HTMLEscapeString(href) + `"`)
+				if !strings.HasPrefix(href, "/") {
+					r.sb.WriteString(` target="_blank" rel="noopener noreferrer"`)
+				}
+				r.sb.WriteString(">")
+				r.inline(text)
+				r.sb.WriteString("</a>")
+				i += n
+				continue
+			}
+
+		case strings.HasPrefix(s[i:], "**"):
+			if end := closing(s[i+2:], "**"); end > 0 {
+				r.sb.WriteString("<strong>")
+				r.inline(s[i+2 : i+2+end])
+				r.sb.WriteString("</strong>")
+				i += end + 4
+				continue
+			}
+			// Both stars are text, not the second an opening one
+			r.text("**")
+			i += 2
+			continue
+
+		case s[i] == '*' || s[i] == '_':
+			// An underscore inside a word, as in snake_case, is just text
+			if s[i] == '_' && wordBefore(s, i) {
+				break
+			}
+			if end := closing(s[i+1:], s[i:i+1]); end > 0 && (s[i] == '*' || !wordAfter(s, i+1+end+1)) {
+				r.sb.WriteString("<em>")
+				r.inline(s[i+1 : i+1+end])
+				r.sb.WriteString("</em>")
+				i += end + 2
+				continue
+			}
+		}
+
+		// Copy text up to the next character that might start some markup
+		next := strings.IndexAny(s[i+1:], `\[*_`)
+		if next < 0 {
+			next = len(s)
+		} else {
+			next += i + 1
+		}
+		r.text(s[i:next])
+		i = next
+	}
+}
+
+// closing returns the index in s of the marker closing an emphasis, which
+// must be preceded by something other than a space, or -1. A single * or _
+// is not closed by half of a doubled one, nor by an escaped one.
+func closing(s, marker string) int {
+	if s == "" || s[0] == ' ' {
+		return -1
+	}
+	for i := 1; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if !strings.HasPrefix(s[i:], marker) || s[i-1] == ' ' {
+			continue
+		}
+		if len(marker) == 1 && (strings.HasPrefix(s[i+1:], marker) || s[i-1] == marker[0]) {
+			i++
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// link parses a [text](url) link at the start of s, returning its parts and
+// length. Only web and site-relative URLs are links; anything else, such as
+// javascript:, is left as text.
+func link(s string) (text, href string, n int, ok bool) {
+	mid := strings.Index(s, "](")
+	if mid < 1 {
+		return "", "", 0, false
+	}
+	end := strings.IndexByte(s[mid+2:], ')')
+	if end < 0 {
+		return "", "", 0, false
+	}
+	text, href = s[1:mid], strings.TrimSpace(s[mid+2:mid+2+end])
+	if strings.ContainsAny(text, "[]") {
+		return "", "", 0, false
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", "", 0, false
+	}
+	switch {
+	case u.Scheme == "http" || u.Scheme == "https":
+	case u.Scheme == "" && u.Host == "" && strings.HasPrefix(href, "/") && !strings.HasPrefix(href, "//"):
+	default:
+		return "", "", 0, false
+	}
+	return text, href, mid + 2 + end + 1, true
+}
+
+// speakable renders a [[text]] or [[text|alt]] span like the lesson page's
+// other clickable target-language text.
+func (r *renderer) speakable(span string) {
+	text, alt, _ := strings.Cut(span, "|")
+	text, alt = strings.TrimSpace(text), strings.TrimSpace(alt)
+	if alt == "" && r.target.ToAlt != nil {
+		alt = r.target.ToAlt(text)
+	}
+	if alt == "" {
+		alt = text
+	}
+
+	r.sb.WriteString(`<button type="button" class="speakable" onclick="playAudio(event, '`)
+	r.text(template.JSEscapeString(text))
+	r.sb.WriteString(`', '`)
+	r.text(template.JSEscapeString(r.target.TTSCode))
+	r.sb.WriteString(`')" title="Listen"><span class="script-latin">`)
+	r.text(text)
+	r.sb.WriteString("</span>")
+	if r.target.DualScript {
+		r.sb.WriteString(`<span class="script-cyrillic">`)
+		r.text(alt)
+		r.sb.WriteString("</span>")
+	}
+	r.sb.WriteString("</button>")
+}
+
+func (r *renderer) text(s string) {
+	r.sb.WriteString(template.HTMLEscapeString(s))
+}
+
+// wordBefore reports whether the character before s[i] is a letter or digit.
+func wordBefore(s string, i int) bool {
+	c, _ := utf8.DecodeLastRuneInString(s[:i])
+	return i > 0 && (unicode.IsLetter(c) || unicode.IsDigit(c))
+}
+
+// wordAfter reports whether s[i] is a letter or digit.
+func wordAfter(s string, i int) bool {
+	c, _ := utf8.DecodeRuneInString(s[i:])
+	return i < len(s) && (unicode.IsLetter(c) || unicode.IsDigit(c))
+}
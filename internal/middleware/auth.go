@@ -65,6 +65,18 @@ func (s *SessionStore) Delete(token string) {
 	s.mu.Unlock()
 }
 
+// DeleteUser ends every session belonging to userID, signing them out
+// everywhere. Used when an admin deletes an account or resets its password.
+func (s *SessionStore) DeleteUser(userID int64) {
+	s.mu.Lock()
+	for token, sess := range s.sessions {
+		if sess.UserID == userID {
+			delete(s.sessions, token)
+		}
+	}
+	s.mu.Unlock()
+}
+
 func (s *SessionStore) SetScript(token, mode string) {
 	s.mu.Lock()
 	if sess, ok := s.sessions[token]; ok {
@@ -100,6 +112,24 @@ func RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// AdminChecker reports whether the user has the admin role.
+type AdminChecker func(ctx context.Context, userID int64) (bool, error)
+
+// RequireAdmin is RequireAuth for the /admin area. Signed-out visitors are
+// sent to the login page; signed-in users who aren't admins get a 404 so the
+// area isn't advertised. The role is looked up on every request, so granting
+// or revoking it takes effect immediately.
+func RequireAdmin(isAdmin AdminChecker, next http.HandlerFunc) http.HandlerFunc {
+	return RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		ok, err := isAdmin(r.Context(), GetUserID(r.Context()))
+		if err != nil || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		next(w, r)
+	})
+}
+
 func GetUserID(ctx context.Context) int64 {
 	id, _ := ctx.Value(userIDKey).(int64)
 	return id
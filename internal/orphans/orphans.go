@@ -0,0 +1,97 @@
+// Package orphans finds saved vocabulary for words that no lesson defines any
+// more. Renaming or removing a vocab item in a lesson file leaves users'
+// vocab_progress and starred_words rows pointing at the old word ID, where
+// they no longer show up or count towards anything. Admins can move them to
+// the word's new ID or delete them.
+package orphans
+
+import (
+	"context"
+	"fmt"
+
+	"speakeasy/internal/db"
+	"speakeasy/internal/lessons"
+)
+
+// Word is a word ID with saved progress or stars that no lesson defines.
+type Word struct {
+	Language      string
+	WordID        string
+	ProgressUsers int64 // users with vocabulary progress on the word
+	StarredUsers  int64 // users who starred it
+}
+
+// Find lists the saved word IDs that no lesson of their language defines,
+// drafts included. Words of languages that aren't loaded are left out: the
+// language may just be missing from this server for now.
+func Find(ctx context.Context, q *db.Queries) ([]Word, error) {
+	rows, err := q.ListSavedWordIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]map[string]bool)
+	var words []Word
+	for _, row := range rows {
+		ids, ok := known[row.Language]
+		if !ok {
+			ids = WordIDs(row.Language)
+			known[row.Language] = ids
+		}
+		if ids == nil || ids[row.WordID] {
+			continue
+		}
+		words = append(words, Word{
+			Language:      row.Language,
+			WordID:        row.WordID,
+			ProgressUsers: row.ProgressUsers,
+			StarredUsers:  row.StarredUsers,
+		})
+	}
+	return words, nil
+}
+
+// WordIDs returns the ID of every vocab item in a language's lessons, drafts
+// included, or nil if the language isn't loaded.
+func WordIDs(slug string) map[string]bool {
+	if lessons.GetLanguage(slug) == nil {
+		return nil
+	}
+	ids := make(map[string]bool)
+	for _, l := range lessons.GetAllLessons(slug) {
+		for _, section := range l.Sections {
+			for _, item := range section.Items {
+				ids[item.ID] = true
+			}
+		}
+	}
+	return ids
+}
+
+// Move reassigns a word's saved progress and stars to newID, which must be a
+// word of the same language. Users who already have progress on newID keep
+// it, and their rows for the old ID are deleted. q should be bound to a
+// transaction.
+func Move(ctx context.Context, q *db.Queries, language, oldID, newID string) error {
+	if !WordIDs(language)[newID] {
+		return fmt.Errorf("no %s lesson has a word %q", language, newID)
+	}
+	if err := q.MoveVocabProgress(ctx, db.MoveVocabProgressParams{NewWordID: newID, Language: language, OldWordID: oldID}); err != nil {
+		return fmt.Errorf("move vocab progress: %w", err)
+	}
+	if err := q.MoveStarredWord(ctx, db.MoveStarredWordParams{NewWordID: newID, Language: language, OldWordID: oldID}); err != nil {
+		return fmt.Errorf("move stars: %w", err)
+	}
+	return Delete(ctx, q, language, oldID)
+}
+
+// Delete removes a word's saved progress and stars. q should be bound to a
+// transaction.
+func Delete(ctx context.Context, q *db.Queries, language, wordID string) error {
+	if err := q.DeleteWordVocabProgress(ctx, db.DeleteWordVocabProgressParams{Language: language, WordID: wordID}); err != nil {
+		return fmt.Errorf("delete vocab progress: %w", err)
+	}
+	if err := q.DeleteWordStars(ctx, db.DeleteWordStarsParams{Language: language, WordID: wordID}); err != nil {
+		return fmt.Errorf("delete stars: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Package translit converts a word from a language's primary script to its
+// alternate one, for languages written in two scripts, so vocabulary only
+// has to be typed once.
+package translit
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// converters maps a language slug to its primary-to-alternate converter.
+var converters = map[string]func(string) string{
+	"serbian": SerbianToCyrillic,
+}
+
+// For returns the converter from a language's primary script to its
+// alternate one, or false if there is none.
+func For(slug string) (func(string) string, bool) {
+	f, ok := converters[slug]
+	return f, ok
+}
+
+// serbianLetters maps Serbian Latin letters, digraphs first, to Cyrillic.
+// Gaj's Latin alphabet and Vuk's Cyrillic correspond one to one, so only the
+// digraphs lj, nj and dž need care.
+var serbianLetters = []struct{ latin, cyrillic string }{
+	{"dž", "џ"}, {"lj", "љ"}, {"nj", "њ"},
+	{"a", "а"}, {"b", "б"}, {"c", "ц"}, {"č", "ч"}, {"ć", "ћ"}, {"d", "д"},
+	{"đ", "ђ"}, {"e", "е"}, {"f", "ф"}, {"g", "г"}, {"h", "х"}, {"i", "и"},
+	{"j", "ј"}, {"k", "к"}, {"l", "л"}, {"m", "м"}, {"n", "н"}, {"o", "о"},
+	{"p", "п"}, {"r", "р"}, {"s", "с"}, {"š", "ш"}, {"t", "т"}, {"u", "у"},
+	{"v", "в"}, {"z", "з"}, {"ž", "ж"},
+}
+
+// SerbianToCyrillic transliterates Serbian Latin text to Cyrillic, keeping
+// case, digits and punctuation. lj, nj and dž always become one letter, which
+// is wrong in the few words where they span a prefix, such as "nadživeti".
+func SerbianToCyrillic(s string) string {
+	var sb strings.Builder
+	for len(s) > 0 {
+		matched := false
+		for _, l := range serbianLetters {
+			if len(s) < len(l.latin) || !strings.EqualFold(s[:len(l.latin)], l.latin) {
+				continue
+			}
+			first, _ := utf8.DecodeRuneInString(s)
+			if unicode.IsUpper(first) {
+				c, size := utf8.DecodeRuneInString(l.cyrillic)
+				sb.WriteRune(unicode.ToUpper(c))
+				sb.WriteString(l.cyrillic[size:])
+			} else {
+				sb.WriteString(l.cyrillic)
+			}
+			s = s[len(l.latin):]
+			matched = true
+			break
+		}
+		if !matched {
+			r, size := utf8.DecodeRuneInString(s)
+			sb.WriteRune(r)
+			s = s[size:]
+		}
+	}
+	return sb.String()
+}
@@ -8,11 +8,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,6 +24,37 @@ type Client struct {
 	audioDir   string
 	mu         sync.Mutex
 	httpClient *http.Client
+
+	// Pre-recorded audio added by language packs, keyed by lang + ":" + text
+	packMu    sync.RWMutex
+	packAudio map[string]packFile
+
+	// Usage counters since startup, for Stats
+	requests  atomic.Int64
+	hits      atomic.Int64
+	apiCalls  atomic.Int64
+	apiErrors atomic.Int64
+}
+
+// packFile is one pre-recorded clip inside a language pack.
+type packFile struct {
+	fsys fs.FS
+	name string
+}
+
+// Stats describes the audio cache on disk and GetAudio usage since the
+// server started.
+type Stats struct {
+	CacheFiles    int
+	CacheBytes    int64
+	OverrideFiles int
+	OverrideBytes int64
+	APIConfigured bool
+
+	Requests  int64 // GetAudio calls
+	Hits      int64 // served from an override or the cache
+	APICalls  int64
+	APIErrors int64
 }
 
 func NewClient(cacheDir, audioDir string) *Client {
@@ -34,6 +68,24 @@ func NewClient(cacheDir, audioDir string) *Client {
 	}
 }
 
+// voices maps the voice names clients may ask for to the SSML gender Google
+// TTS picks a voice by.
+var voices = map[string]string{
+	"female": "FEMALE",
+	"male":   "MALE",
+}
+
+// Voice returns the gender GetAudio takes for a voice name such as "male",
+// in any case, or false if there is no such voice. No name is the default
+// female voice.
+func Voice(name string) (gender string, ok bool) {
+	if name == "" {
+		return "FEMALE", true
+	}
+	gender, ok = voices[strings.ToLower(name)]
+	return gender, ok
+}
+
 func (c *Client) cacheKey(text, lang, gender string) string {
 	h := sha256.Sum256([]byte(lang + ":" + gender + ":" + text))
 	return hex.EncodeToString(h[:16])
@@ -48,32 +100,29 @@ func (c *Client) GetAudio(text, lang, gender string) ([]byte, string, error) {
 	if gender == "" {
 		gender = "FEMALE"
 	}
-	// Check for pre-recorded override
-	key := c.cacheKey(text, lang, gender)
-	overridePath := filepath.Join(c.audioDir, key+".mp3")
-	if data, err := os.ReadFile(overridePath); err == nil {
-		return data, "audio/mpeg", nil
-	}
-
-	// Check cache
-	cachePath := filepath.Join(c.cacheDir, key+".mp3")
-	if data, err := os.ReadFile(cachePath); err == nil {
+	c.requests.Add(1)
+	if data, ok := c.CachedAudio(text, lang, gender); ok {
+		c.hits.Add(1)
 		return data, "audio/mpeg", nil
 	}
+	cachePath := filepath.Join(c.cacheDir, c.cacheKey(text, lang, gender)+".mp3")
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	// Double-check cache after acquiring lock
 	if data, err := os.ReadFile(cachePath); err == nil {
+		c.hits.Add(1)
 		return data, "audio/mpeg", nil
 	}
 
 	// Try Google Cloud TTS if API key is available
 	apiKey := os.Getenv("GOOGLE_TTS_API_KEY")
 	if apiKey != "" {
+		c.apiCalls.Add(1)
 		data, err := c.callGoogleTTS(text, lang, gender, apiKey)
 		if err != nil {
+			c.apiErrors.Add(1)
 			log.Printf("TTS API error for %q: %v", text, err)
 		} else {
 			os.WriteFile(cachePath, data, 0o644)
@@ -85,6 +134,83 @@ func (c *Client) GetAudio(text, lang, gender string) ([]byte, string, error) {
 	return nil, "", fmt.Errorf("TTS unavailable for %q", text)
 }
 
+// AddOverrides registers pre-recorded audio from a language pack. files maps
+// the spoken text to an MP3 file in fsys. Pack audio is served for lang in
+// any voice, ahead of the audio directory and the cache.
+func (c *Client) AddOverrides(lang string, fsys fs.FS, files map[string]string) {
+	c.packMu.Lock()
+	defer c.packMu.Unlock()
+	if c.packAudio == nil {
+		c.packAudio = make(map[string]packFile)
+	}
+	for text, name := range files {
+		c.packAudio[lang+":"+text] = packFile{fsys: fsys, name: name}
+	}
+}
+
+// CachedAudio returns pre-recorded or previously synthesized audio for the
+// text without calling the TTS API. ok is false if neither exists.
+func (c *Client) CachedAudio(text, lang, gender string) (data []byte, ok bool) {
+	if gender == "" {
+		gender = "FEMALE"
+	}
+	// Check language pack recordings
+	c.packMu.RLock()
+	pf, ok := c.packAudio[lang+":"+text]
+	c.packMu.RUnlock()
+	if ok {
+		if data, err := fs.ReadFile(pf.fsys, pf.name); err == nil {
+			return data, true
+		}
+	}
+
+	key := c.cacheKey(text, lang, gender)
+
+	// Check for pre-recorded override
+	if data, err := os.ReadFile(filepath.Join(c.audioDir, key+".mp3")); err == nil {
+		return data, true
+	}
+
+	// Check cache
+	if data, err := os.ReadFile(filepath.Join(c.cacheDir, key+".mp3")); err == nil {
+		return data, true
+	}
+	return nil, false
+}
+
+// Stats reports cache sizes by scanning the cache and override directories,
+// along with the usage counters.
+func (c *Client) Stats() Stats {
+	st := Stats{
+		APIConfigured: os.Getenv("GOOGLE_TTS_API_KEY") != "",
+		Requests:      c.requests.Load(),
+		Hits:          c.hits.Load(),
+		APICalls:      c.apiCalls.Load(),
+		APIErrors:     c.apiErrors.Load(),
+	}
+	st.CacheFiles, st.CacheBytes = mp3Usage(c.cacheDir)
+	st.OverrideFiles, st.OverrideBytes = mp3Usage(c.audioDir)
+	return st
+}
+
+// mp3Usage counts the .mp3 files directly in dir and their total size.
+func mp3Usage(dir string) (files int, size int64) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, 0
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".mp3") {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			files++
+			size += info.Size()
+		}
+	}
+	return files, size
+}
+
 func (c *Client) callGoogleTTS(text, lang, gender, apiKey string) ([]byte, error) {
 	url := "https://texttospeech.googleapis.com/v1/text:synthesize?key=" + apiKey
 
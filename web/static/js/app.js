@@ -1,12 +1,13 @@
 // SpeakEasy - Language Learning App JavaScript
 
-// Audio playback for TTS
-function playAudio(e, text, lang) {
+// Audio playback for TTS, in a dialogue speaker's voice ("female" or "male")
+// if one is given
+function playAudio(e, text, lang, voice) {
     e.stopPropagation();
     var btn = e.currentTarget;
     btn.classList.add('playing');
 
-    var audio = new Audio('/api/tts?text=' + encodeURIComponent(text) + '&lang=' + encodeURIComponent(lang));
+    var audio = new Audio(ttsURL(text, lang, voice));
     audio.addEventListener('ended', function() {
         btn.classList.remove('playing');
     });
@@ -18,6 +19,12 @@ function playAudio(e, text, lang) {
     });
 }
 
+function ttsURL(text, lang, voice) {
+    var url = '/api/tts?text=' + encodeURIComponent(text) + '&lang=' + encodeURIComponent(lang);
+    if (voice) url += '&gender=' + encodeURIComponent(voice);
+    return url;
+}
+
 // Script toggle (Cyrillic / Latin / Both)
 function setScript(e, mode) {
     document.body.className = document.body.className
@@ -195,6 +202,127 @@ function selectOption(questionIdx, optionIdx) {
     };
 })();
 
+// Dialogues: play the whole conversation line by line, or role-play one
+// speaker, whose lines are hidden until the learner types them
+(function() {
+    var playing = null; // { dialogue, button, audio, waiting }
+
+    // normalize ignores case, punctuation and spacing when comparing lines
+    function normalize(s) {
+        return s.toLowerCase().replace(/\p{P}/gu, ' ').replace(/\s+/g, ' ').trim();
+    }
+
+    function stop() {
+        if (!playing) return;
+        if (playing.audio) playing.audio.pause();
+        playing.dialogue.querySelectorAll('.dialogue-line.speaking').forEach(function(line) {
+            line.classList.remove('speaking');
+        });
+        playing.button.innerHTML = '&#9654; Play dialogue';
+        playing = null;
+    }
+
+    // playFrom speaks the lines from index i on. In role-play it waits at
+    // the learner's next hidden line until they answer it.
+    function playFrom(state, i) {
+        if (playing !== state) return;
+        var lines = state.dialogue.querySelectorAll('.dialogue-line');
+        lines.forEach(function(line) { line.classList.remove('speaking'); });
+        if (i >= lines.length) {
+            stop();
+            return;
+        }
+
+        var line = lines[i];
+        if (line.classList.contains('hidden-line')) {
+            state.waiting = i;
+            line.querySelector('.dialogue-answer input').focus();
+            return;
+        }
+        line.classList.add('speaking');
+        line.scrollIntoView({ block: 'nearest', behavior: 'smooth' });
+
+        var done = false;
+        var next = function() {
+            if (done) return;
+            done = true;
+            playFrom(state, i + 1);
+        };
+        state.audio = new Audio(ttsURL(line.dataset.text, line.dataset.lang, line.dataset.voice));
+        state.audio.addEventListener('ended', next);
+        state.audio.addEventListener('error', next);
+        state.audio.play().catch(next);
+    }
+
+    window.playDialogue = function(event) {
+        var button = event.currentTarget;
+        var dialogue = button.closest('[data-dialogue]');
+        var wasPlaying = playing && playing.dialogue === dialogue;
+        stop();
+        if (wasPlaying) return;
+
+        playing = { dialogue: dialogue, button: button, audio: null, waiting: null };
+        button.innerHTML = '&#9632; Stop';
+        playFrom(playing, 0);
+    };
+
+    // setDialogueRole hides the chosen speaker's lines for the learner to
+    // type, or shows them all again if that speaker was already chosen
+    window.setDialogueRole = function(event) {
+        var button = event.currentTarget;
+        var dialogue = button.closest('[data-dialogue]');
+        var role = button.classList.contains('active') ? '' : button.dataset.role;
+        if (playing && playing.dialogue === dialogue) stop();
+
+        dialogue.querySelectorAll('.dialogue-roles button').forEach(function(b) {
+            b.classList.toggle('active', b.dataset.role === role);
+        });
+        dialogue.classList.toggle('roleplay', role !== '');
+        dialogue.querySelectorAll('.dialogue-line').forEach(function(line) {
+            line.classList.remove('answered', 'revealed');
+            line.classList.toggle('hidden-line', line.dataset.speaker === role);
+            var input = line.querySelector('.dialogue-answer input');
+            input.value = '';
+            input.classList.remove('wrong');
+        });
+    };
+
+    window.checkDialogueLine = function(event) {
+        event.preventDefault();
+        var line = event.currentTarget.closest('.dialogue-line');
+        var input = line.querySelector('.dialogue-answer input');
+        var answer = normalize(input.value);
+        if (answer === '') return;
+
+        if (answer === normalize(line.dataset.text) || (line.dataset.alt && answer === normalize(line.dataset.alt))) {
+            reveal(line, 'answered');
+        } else {
+            input.classList.add('wrong');
+            input.select();
+        }
+    };
+
+    window.revealDialogueLine = function(event) {
+        reveal(event.currentTarget.closest('.dialogue-line'), 'revealed');
+    };
+
+    // reveal shows a hidden line and speaks it, carrying on with the rest of
+    // the dialogue if it was waiting for this line
+    function reveal(line, how) {
+        line.classList.remove('hidden-line');
+        line.classList.add(how);
+
+        var dialogue = line.closest('[data-dialogue]');
+        var index = Array.prototype.indexOf.call(dialogue.querySelectorAll('.dialogue-line'), line);
+        if (playing && playing.dialogue === dialogue && playing.waiting === index) {
+            playing.waiting = null;
+            playFrom(playing, index);
+            return;
+        }
+        new Audio(ttsURL(line.dataset.text, line.dataset.lang, line.dataset.voice)).play().catch(function() {});
+    }
+})();
+
 // Confetti effect
 function showConfetti() {
     var colors = ['#7C3AED', '#F59E0B', '#14B8A6', '#3B82F6', '#EF4444', '#10B981'];
@@ -263,8 +391,25 @@ document.addEventListener('DOMContentLoaded', function() {
         });
     }
 
-    // Auto-trigger confetti on results page if passed
-    if (document.querySelector('.results-score.pass')) {
+    // Auto-trigger confetti on results page if passed, or for a new badge
+    if (document.querySelector('.results-score.pass, .achievement.badge-new')) {
         setTimeout(showConfetti, 500);
     }
+
+    // Lesson pages count towards the daily goal, one minute at a time
+    if (document.querySelector('[data-reading-timer]')) {
+        startReadingTimer();
+    }
 });
+
+// Report reading time while the lesson page is visible in the foreground
+function startReadingTimer() {
+    setInterval(function() {
+        if (document.visibilityState !== 'visible') return;
+        fetch('/api/activity/heartbeat', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/x-www-form-urlencoded' },
+            body: 'seconds=60'
+        });
+    }, 60000);
+}